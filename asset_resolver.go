@@ -0,0 +1,72 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import "fmt"
+
+// AssetResolver retrieves information about an asset based on an asset
+// ID. AssetLibrary is the resolver backed by the Attribution Engine
+// backend service, but callers can implement their own, e.g. on top of
+// a local cache or their own metadata store, and combine them using
+// AssetResolverChain.
+type AssetResolver interface {
+	GetAsset(id uint64) (*Asset, error)
+}
+
+var _ AssetResolver = (*AssetLibrary)(nil)
+
+// WithAssetResolvers makes Client.GetAsset try the given resolvers, in
+// order, before falling back to Client.AssetLibrary. This way assets
+// can be looked up in e.g. a local cache first, and the backend service
+// is only asked for the ones that aren't there.
+func WithAssetResolvers(resolvers ...AssetResolver) ClientOption {
+	return func(o *clientOptions) {
+		o.assetResolvers = append(o.assetResolvers, resolvers...)
+	}
+}
+
+// AssetResolverChain is an ordered list of asset resolvers that is
+// itself an AssetResolver. A typical chain looks like this:
+//
+//	resolver := pexae.AssetResolverChain{cache, store, client.AssetLibrary}
+//	asset, err := resolver.GetAsset(match.AssetID)
+//
+// Client.GetAsset uses such a chain, built from the resolvers passed to
+// WithAssetResolvers followed by Client.AssetLibrary.
+type AssetResolverChain []AssetResolver
+
+// GetAsset tries the resolvers in order and returns the asset from the
+// first one that succeeds. A resolver that returns an error (or a nil
+// asset) is skipped, and the next one is tried.
+//
+// If all resolvers fail, the first error whose Code isn't
+// StatusNotFound is returned. This way a broken resolver isn't hidden
+// behind "not found" errors from the others. If every resolver reported
+// the asset as missing, or the chain is empty, an Error with
+// StatusNotFound is returned.
+func (x AssetResolverChain) GetAsset(id uint64) (*Asset, error) {
+	var firstErr error
+
+	for _, r := range x {
+		asset, err := r.GetAsset(id)
+		if err == nil && asset != nil {
+			return asset, nil
+		}
+		if firstErr == nil && err != nil && !isNotFound(err) {
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, &Error{
+		Code:    StatusNotFound,
+		Message: fmt.Sprintf("asset %d not found", id),
+	}
+}
+
+func isNotFound(err error) bool {
+	e, ok := err.(*Error)
+	return ok && e != nil && e.Code == StatusNotFound
+}
@@ -0,0 +1,70 @@
+package pexae
+
+import (
+	"errors"
+	"testing"
+)
+
+type testResolver map[uint64]*Asset
+
+func (x testResolver) GetAsset(id uint64) (*Asset, error) {
+	if asset, ok := x[id]; ok {
+		return asset, nil
+	}
+	return nil, &Error{Code: StatusNotFound, Message: "not found"}
+}
+
+type failingResolver struct {
+	err error
+}
+
+func (x failingResolver) GetAsset(id uint64) (*Asset, error) {
+	return nil, x.err
+}
+
+func TestAssetResolverChain(t *testing.T) {
+	a1 := &Asset{Type: AssetTypeRecording}
+	a2 := &Asset{Type: AssetTypeVideo}
+	broken := errors.New("broken")
+
+	chain := AssetResolverChain{
+		testResolver{1: a1},
+		failingResolver{broken},
+		testResolver{1: a2, 2: a2},
+	}
+
+	if asset, err := chain.GetAsset(1); err != nil || asset != a1 {
+		t.Fatalf("expected the asset from the first resolver, got %+v, %+v", asset, err)
+	}
+	if asset, err := chain.GetAsset(2); err != nil || asset != a2 {
+		t.Fatalf("expected the asset from the last resolver, got %+v, %+v", asset, err)
+	}
+	if _, err := chain.GetAsset(3); err != broken {
+		t.Fatalf("expected %+v, got %+v", broken, err)
+	}
+
+	_, err := AssetResolverChain{testResolver{}}.GetAsset(3)
+	if e, ok := err.(*Error); !ok || e.Code != StatusNotFound {
+		t.Fatalf("expected a not found error, got %+v", err)
+	}
+}
+
+func TestClientGetAsset(t *testing.T) {
+	cached := &Asset{Type: AssetTypeRecording}
+
+	client, err := NewMockserverClient("client01", "secret01", WithAssetResolvers(
+		testResolver{1: cached},
+		failingResolver{&Error{Code: StatusNotFound, Message: "not found"}},
+	))
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	defer client.Close()
+
+	if asset, err := client.GetAsset(1); err != nil || asset != cached {
+		t.Fatalf("expected the asset from the first resolver, got %+v, %+v", asset, err)
+	}
+	if n := len(client.assets); n != 3 || client.assets[n-1] != client.AssetLibrary {
+		t.Fatalf("expected AssetLibrary to be the last of 3 resolvers, got %+v", client.assets)
+	}
+}
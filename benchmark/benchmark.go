@@ -0,0 +1,172 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+// Package benchmark provides a reusable harness for measuring the
+// performance of metadata searches. It only uses the public API of the
+// SDK and doesn't perform any operation besides searching, so it is
+// safe to run against any environment the client is configured for.
+//
+//	client, err := pexae.NewClient(clientID, clientSecret)
+//	if err != nil {
+//	    panic(err)
+//	}
+//	defer client.Close()
+//
+//	report, err := benchmark.Run(client, fingerprints, &benchmark.Options{
+//	    Concurrency: 8,
+//	    Searches:    1000,
+//	})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	fmt.Printf("%+v\n", report)
+package benchmark
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	pexae "github.com/Pexeso/ae-sdk-go"
+)
+
+// Options configures a benchmark run.
+type Options struct {
+	// The number of searches that run at the same time. Defaults to 1.
+	Concurrency int
+
+	// The total number of searches to perform. The fingerprints are
+	// used in a round-robin fashion. Defaults to the number of
+	// fingerprints passed to Run.
+	Searches int
+}
+
+// Report summarizes a benchmark run. The latency of a search is
+// measured from calling MetadataSearch.Start until MetadataSearchFuture.Get
+// returns. Only searches that succeeded are included in the latency
+// percentiles.
+type Report struct {
+	// The number of searches that were performed.
+	Searches int
+
+	// The number of searches that returned an error.
+	Errors int
+
+	// The wall-clock duration of the whole run.
+	Duration time.Duration
+
+	// The number of searches that succeeded per second. Searches that
+	// returned an error are not counted.
+	Throughput float64
+
+	// Latency percentiles of the successful searches.
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// Run performs metadata searches using the given client and
+// fingerprints, and reports the achieved throughput and latency. It
+// blocks until all searches are finished.
+func Run(client *pexae.Client, fingerprints []*pexae.Fingerprint, opts *Options) (*Report, error) {
+	if client == nil {
+		return nil, errors.New("client is required")
+	}
+	if len(fingerprints) == 0 {
+		return nil, errors.New("at least one fingerprint is required")
+	}
+
+	concurrency := 1
+	searches := len(fingerprints)
+	if opts != nil {
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+		if opts.Searches > 0 {
+			searches = opts.Searches
+		}
+	}
+
+	jobs := make(chan *pexae.Fingerprint)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < searches; i++ {
+			jobs <- fingerprints[i%len(fingerprints)]
+		}
+	}()
+
+	var m sync.Mutex
+	var wg sync.WaitGroup
+	var latencies []time.Duration
+	var errs int
+
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ft := range jobs {
+				latency, err := search(client, ft)
+
+				m.Lock()
+				if err != nil {
+					errs++
+				} else {
+					latencies = append(latencies, latency)
+				}
+				m.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	duration := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+
+	report := &Report{
+		Searches: searches,
+		Errors:   errs,
+		Duration: duration,
+		P50:      percentile(latencies, 50),
+		P90:      percentile(latencies, 90),
+		P99:      percentile(latencies, 99),
+		Max:      percentile(latencies, 100),
+	}
+	if duration > 0 {
+		report.Throughput = float64(searches-errs) / duration.Seconds()
+	}
+	return report, nil
+}
+
+func search(client *pexae.Client, ft *pexae.Fingerprint) (time.Duration, error) {
+	start := time.Now()
+
+	fut, err := client.MetadataSearch.Start(&pexae.MetadataSearchRequest{
+		Fingerprint: ft,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := fut.Get(); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// percentile returns the p-th percentile of the sorted latencies using
+// the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
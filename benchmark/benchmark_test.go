@@ -0,0 +1,102 @@
+package benchmark
+
+import (
+	"testing"
+	"time"
+
+	pexae "github.com/Pexeso/ae-sdk-go"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 10; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		p        int
+		expected time.Duration
+	}{
+		{0, 1 * time.Millisecond},
+		{1, 1 * time.Millisecond},
+		{10, 1 * time.Millisecond},
+		{11, 2 * time.Millisecond},
+		{50, 5 * time.Millisecond},
+		{90, 9 * time.Millisecond},
+		{99, 10 * time.Millisecond},
+		{100, 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(latencies, tt.p); got != tt.expected {
+			t.Fatalf("p%d: expected %v, got %v", tt.p, tt.expected, got)
+		}
+	}
+
+	if got := percentile(latencies[:1], 50); got != time.Millisecond {
+		t.Fatalf("expected %v, got %v", time.Millisecond, got)
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Fatalf("expected 0, got %v", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	client, err := pexae.NewMockserverClient("client01", "secret01")
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	defer client.Close()
+
+	var fingerprints []*pexae.Fingerprint
+	for _, dump := range []string{"first", "second", "third"} {
+		ft, err := pexae.LoadDumpedFingerprint([]byte(dump))
+		if err != nil {
+			t.Fatalf("expected no error, got %+v", err)
+		}
+		defer ft.Close()
+		fingerprints = append(fingerprints, ft)
+	}
+
+	tests := []struct {
+		opts     *Options
+		searches int
+	}{
+		{nil, 3},
+		{&Options{Concurrency: 2}, 3},
+		{&Options{Concurrency: 4, Searches: 10}, 10},
+	}
+	for _, tt := range tests {
+		report, err := Run(client, fingerprints, tt.opts)
+		if err != nil {
+			t.Fatalf("%+v: expected no error, got %+v", tt.opts, err)
+		}
+		if report.Searches != tt.searches {
+			t.Fatalf("%+v: expected %d searches, got %d", tt.opts, tt.searches, report.Searches)
+		}
+		if report.Errors != 0 {
+			t.Fatalf("%+v: expected no errors, got %d", tt.opts, report.Errors)
+		}
+		if report.Throughput <= 0 {
+			t.Fatalf("%+v: expected a positive throughput, got %v", tt.opts, report.Throughput)
+		}
+		if report.P50 > report.P90 || report.P90 > report.P99 || report.P99 > report.Max {
+			t.Fatalf("%+v: expected ordered percentiles, got %+v", tt.opts, report)
+		}
+	}
+}
+
+func TestRunInvalidArguments(t *testing.T) {
+	if _, err := Run(nil, nil, nil); err == nil {
+		t.Fatal("expected an error for a nil client, got nil")
+	}
+
+	client, err := pexae.NewMockserverClient("client01", "secret01")
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	defer client.Close()
+
+	if _, err := Run(client, nil, nil); err == nil {
+		t.Fatal("expected an error for no fingerprints, got nil")
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by the Start methods without contacting the
+// backend service while the client's circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+const (
+	defaultCircuitBreakerFailureThreshold = 5
+	defaultCircuitBreakerCooldown         = 30 * time.Second
+)
+
+// CircuitBreakerSettings configures the circuit breaker enabled by the
+// WithCircuitBreaker option.
+type CircuitBreakerSettings struct {
+	// The number of consecutive backend failures after which the
+	// breaker opens. Defaults to 5.
+	FailureThreshold int
+
+	// How long the breaker stays open before a single probe search is
+	// let through. Defaults to 30 seconds.
+	Cooldown time.Duration
+}
+
+// CircuitState is the state of the client's circuit breaker.
+type CircuitState int
+
+const (
+	// Searches are let through to the backend service.
+	CircuitClosed = CircuitState(0)
+
+	// Searches fail immediately with ErrCircuitOpen.
+	CircuitOpen = CircuitState(1)
+
+	// The cooldown has elapsed and a single probe search is let through.
+	// The breaker closes once the probe's result is retrieved and opens
+	// again if the probe fails.
+	CircuitHalfOpen = CircuitState(2)
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// WithCircuitBreaker enables a circuit breaker around the calls to the
+// backend service. After FailureThreshold consecutive failures, all
+// LicenseSearch.Start and MetadataSearch.Start calls fail immediately
+// with ErrCircuitOpen until the cooldown elapses. Only errors caused by
+// the backend being unavailable (StatusDeadlineExceeded,
+// StatusConnectionError, StatusInternalError and StatusLookupTimedOut)
+// are counted as failures, both from Start and from the futures' Get.
+// Only a successfully retrieved result resets the failures and closes
+// the breaker, since a search can be started successfully and still
+// time out.
+func WithCircuitBreaker(settings CircuitBreakerSettings) ClientOption {
+	return func(o *clientOptions) {
+		if settings.FailureThreshold <= 0 {
+			settings.FailureThreshold = defaultCircuitBreakerFailureThreshold
+		}
+		if settings.Cooldown <= 0 {
+			settings.Cooldown = defaultCircuitBreakerCooldown
+		}
+		o.circuitBreaker = &settings
+	}
+}
+
+type circuitBreaker struct {
+	settings CircuitBreakerSettings
+	now      func() time.Time
+
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+	probedAt time.Time
+	m        sync.Mutex
+}
+
+func newCircuitBreaker(settings *CircuitBreakerSettings) *circuitBreaker {
+	if settings == nil {
+		return nil
+	}
+	return &circuitBreaker{
+		settings: *settings,
+		now:      time.Now,
+	}
+}
+
+// allow returns ErrCircuitOpen if the call must not be let through to
+// the backend. It is safe to call on a nil breaker.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.update()
+
+	switch b.state {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		b.probedAt = b.now()
+	}
+	return nil
+}
+
+// recordStart updates the breaker with the outcome of a Start call. A
+// successful Start doesn't say anything about whether the search will
+// complete, so it neither resets the failures nor closes the breaker.
+// A half-open probe lasts until its result is recorded by recordGet.
+// It is safe to call on a nil breaker.
+func (b *circuitBreaker) recordStart(err error) {
+	if b == nil {
+		return
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if isBackendFailure(err) {
+		b.fail()
+		return
+	}
+
+	// The search was rejected for a reason unrelated to the backend
+	// being unavailable, so no Get will follow to resolve the probe.
+	if err != nil {
+		b.probing = false
+	}
+}
+
+// release gives up the probe handed out by allow, if any, when the call
+// is abandoned before reaching the backend, or before its outcome can
+// be recorded. It is safe to call on a nil breaker.
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.probing = false
+}
+
+// recordGet updates the breaker with the outcome of retrieving a search
+// result. It is safe to call on a nil breaker.
+func (b *circuitBreaker) recordGet(err error) {
+	if b == nil {
+		return
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if isBackendFailure(err) {
+		b.fail()
+		return
+	}
+
+	// Errors unrelated to the backend being unavailable, such as
+	// StatusNotFound, don't say whether the backend has recovered, so
+	// they only resolve the probe.
+	if err != nil {
+		b.probing = false
+		return
+	}
+
+	b.state = CircuitClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// fail must be called with the mutex held.
+func (b *circuitBreaker) fail() {
+	b.probing = false
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.settings.FailureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+func (b *circuitBreaker) State() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.update()
+	return b.state
+}
+
+// update must be called with the mutex held.
+func (b *circuitBreaker) update() {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.settings.Cooldown {
+		b.state = CircuitHalfOpen
+		b.probing = false
+	}
+
+	// The probe's result was never retrieved, e.g. because the caller
+	// didn't call Get on its future. Let another probe through instead
+	// of staying half-open forever.
+	if b.state == CircuitHalfOpen && b.probing && b.now().Sub(b.probedAt) >= b.settings.Cooldown {
+		b.probing = false
+	}
+}
+
+func isBackendFailure(err error) bool {
+	e, ok := err.(*Error)
+	if !ok || e == nil {
+		return false
+	}
+
+	switch e.Code {
+	case StatusDeadlineExceeded, StatusConnectionError, StatusInternalError, StatusLookupTimedOut:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,194 @@
+package pexae
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestCircuitBreaker(now *time.Time) *circuitBreaker {
+	b := newCircuitBreaker(&CircuitBreakerSettings{
+		FailureThreshold: 2,
+		Cooldown:         time.Minute,
+	})
+	b.now = func() time.Time { return *now }
+	return b
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTestCircuitBreaker(&now)
+
+	failure := &Error{Code: StatusConnectionError}
+
+	// Errors caused by the caller don't count as failures.
+	b.recordStart(&Error{Code: StatusInvalidInput})
+	b.recordStart(failure)
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("expected %s, got %s", CircuitClosed, state)
+	}
+
+	b.recordStart(failure)
+	if state := b.State(); state != CircuitOpen {
+		t.Fatalf("expected %s, got %s", CircuitOpen, state)
+	}
+	if err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %+v", err)
+	}
+
+	// A failed probe opens the breaker again.
+	now = now.Add(time.Minute)
+	if state := b.State(); state != CircuitHalfOpen {
+		t.Fatalf("expected %s, got %s", CircuitHalfOpen, state)
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected the probe to be let through, got %+v", err)
+	}
+	if err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("expected only a single probe, got %+v", err)
+	}
+	b.recordStart(failure)
+	if state := b.State(); state != CircuitOpen {
+		t.Fatalf("expected %s, got %s", CircuitOpen, state)
+	}
+
+	// The probe lasts until its result is retrieved.
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected the probe to be let through, got %+v", err)
+	}
+	b.recordStart(nil)
+	if state := b.State(); state != CircuitHalfOpen {
+		t.Fatalf("expected a started probe to keep the breaker %s, got %s", CircuitHalfOpen, state)
+	}
+	if err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("expected only a single probe, got %+v", err)
+	}
+	b.recordGet(nil)
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("expected %s, got %s", CircuitClosed, state)
+	}
+}
+
+func TestCircuitBreakerGetTimeouts(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTestCircuitBreaker(&now)
+
+	// Every search is started successfully, but times out when its
+	// result is retrieved.
+	for i := 0; i < 2; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatalf("expected search %d to be let through, got %+v", i, err)
+		}
+		b.recordStart(nil)
+		b.recordGet(&Error{Code: StatusLookupTimedOut})
+	}
+
+	if state := b.State(); state != CircuitOpen {
+		t.Fatalf("expected %s, got %s", CircuitOpen, state)
+	}
+
+	// A probe whose result is never retrieved doesn't keep the breaker
+	// half-open forever.
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected the probe to be let through, got %+v", err)
+	}
+	b.recordStart(nil)
+
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected another probe to be let through, got %+v", err)
+	}
+}
+
+func TestCircuitBreakerGetOtherErrors(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTestCircuitBreaker(&now)
+
+	failure := &Error{Code: StatusLookupTimedOut}
+	other := &Error{Code: StatusLookupFailed}
+
+	// Errors that aren't backend failures don't reset the failures.
+	b.recordGet(failure)
+	b.recordGet(&Error{Code: StatusNotFound})
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("expected %s, got %s", CircuitClosed, state)
+	}
+	b.recordGet(failure)
+	if state := b.State(); state != CircuitOpen {
+		t.Fatalf("expected %s, got %s", CircuitOpen, state)
+	}
+
+	// Nor do they close the breaker, they only resolve the probe.
+	now = now.Add(time.Minute)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected the probe to be let through, got %+v", err)
+	}
+	b.recordStart(nil)
+	b.recordGet(other)
+	if state := b.State(); state != CircuitHalfOpen {
+		t.Fatalf("expected %s, got %s", CircuitHalfOpen, state)
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected another probe to be let through, got %+v", err)
+	}
+	b.recordStart(nil)
+	b.recordGet(nil)
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("expected %s, got %s", CircuitClosed, state)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	var b *circuitBreaker
+	b.recordStart(&Error{Code: StatusConnectionError})
+	b.recordGet(&Error{Code: StatusConnectionError})
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if state := b.State(); state != CircuitClosed {
+		t.Fatalf("expected %s, got %s", CircuitClosed, state)
+	}
+}
+
+func TestWithCircuitBreaker(t *testing.T) {
+	client, err := NewMockserverClient("client01", "secret01", WithCircuitBreaker(CircuitBreakerSettings{
+		FailureThreshold: 1,
+		Cooldown:         time.Minute,
+	}))
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	defer client.Close()
+
+	now := time.Unix(0, 0)
+	client.breaker.now = func() time.Time { return now }
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	client.breaker.recordStart(&Error{Code: StatusConnectionError})
+	if state := client.CircuitState(); state != CircuitOpen {
+		t.Fatalf("expected %s, got %s", CircuitOpen, state)
+	}
+	if _, err := client.MetadataSearch.Start(&MetadataSearchRequest{Fingerprint: ft}); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %+v", err)
+	}
+	if _, err := client.LicenseSearch.Start(&LicenseSearchRequest{Fingerprint: ft}); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %+v", err)
+	}
+
+	// A probe abandoned because the client is closed doesn't hold up the
+	// next one.
+	now = now.Add(time.Minute)
+	client.ops.close()
+	if _, err := client.MetadataSearch.Start(&MetadataSearchRequest{Fingerprint: ft}); err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled, got %+v", err)
+	}
+	if _, err := client.LicenseSearch.Start(&LicenseSearchRequest{Fingerprint: ft}); err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled, got %+v", err)
+	}
+	if err := client.breaker.allow(); err != nil {
+		t.Fatalf("expected the probe to be let through, got %+v", err)
+	}
+}
@@ -9,7 +9,10 @@ package pexae
 // #include <pex/ae/sdk/c/asset_library.h>
 // #include <stdlib.h>
 import "C"
-import "unsafe"
+import (
+	"context"
+	"unsafe"
+)
 
 // Client serves as an entry point to all operations that
 // communicate with the Attribution Engine backend service. It
@@ -31,12 +34,26 @@ type Client struct {
 	// struct directly.
 	MetadataSearch *MetadataSearch
 
-	c *C.AE_Client
+	c            *C.AE_Client
+	breaker      *circuitBreaker
+	fingerprints *fingerprintCache
+	ops          *operations
+	assets       AssetResolverChain
+}
+
+// ClientOption configures optional behavior of a Client. Options are
+// passed to NewClient or NewMockserverClient.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	circuitBreaker       *CircuitBreakerSettings
+	fingerprintRetention int
+	assetResolvers       []AssetResolver
 }
 
 // NewClient initializes connections and authenticates with the
 // backend service with the credentials provided as arguments.
-func NewClient(clientID, clientSecret string) (*Client, error) {
+func NewClient(clientID, clientSecret string, opts ...ClientOption) (*Client, error) {
 	cStatus := C.AE_Status_New()
 	if cStatus == nil {
 		panic("out of memory")
@@ -59,10 +76,19 @@ func NewClient(clientID, clientSecret string) (*Client, error) {
 		C.free(unsafe.Pointer(cClient))
 		return nil, err
 	}
-	return buildClient(cClient), nil
+	return buildClient(cClient, opts), nil
 }
 
-func buildClient(cClient *C.AE_Client) *Client {
+func buildClient(cClient *C.AE_Client, opts []ClientOption) *Client {
+	var o clientOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	breaker := newCircuitBreaker(o.circuitBreaker)
+	fingerprints := newFingerprintCache(o.fingerprintRetention)
+	ops := newOperations()
+
 	cAssetLibrary := C.AE_AssetLibrary_New(cClient)
 	if cAssetLibrary == nil {
 		panic("out of memory")
@@ -78,25 +104,116 @@ func buildClient(cClient *C.AE_Client) *Client {
 		panic("out of memory")
 	}
 
+	assetLibrary := &AssetLibrary{
+		c: cAssetLibrary,
+	}
+
 	return &Client{
-		c: cClient,
-		AssetLibrary: &AssetLibrary{
-			c: cAssetLibrary,
-		},
+		c:            cClient,
+		breaker:      breaker,
+		fingerprints: fingerprints,
+		ops:          ops,
+		assets:       append(AssetResolverChain(o.assetResolvers), assetLibrary),
+		AssetLibrary: assetLibrary,
 		LicenseSearch: &LicenseSearch{
-			c: cLicenseSearch,
+			c:            cLicenseSearch,
+			breaker:      breaker,
+			fingerprints: fingerprints,
+			ops:          ops,
 		},
 		MetadataSearch: &MetadataSearch{
-			c: cMetadataSearch,
+			c:            cMetadataSearch,
+			breaker:      breaker,
+			fingerprints: fingerprints,
+			ops:          ops,
 		},
 	}
 }
 
+// CircuitState returns the current state of the circuit breaker enabled
+// by the WithCircuitBreaker option. If the option wasn't used,
+// CircuitClosed is always returned.
+func (x *Client) CircuitState() CircuitState {
+	return x.breaker.State()
+}
+
+// GetAsset retrieves information about an asset based on an asset ID.
+// The resolvers passed to the WithAssetResolvers option are tried
+// first, in order, and AssetLibrary is only used when none of them has
+// the asset. Errors are reported as by AssetResolverChain.GetAsset.
+func (x *Client) GetAsset(id uint64) (*Asset, error) {
+	return x.assets.GetAsset(id)
+}
+
+// FingerprintForLookup returns the serialized fingerprint that was used
+// in the search identified by lookupID. The fingerprints are only
+// retained when the client was created with the WithFingerprintRetention
+// option, and only for the number of most recent searches specified
+// there. The returned dump can be loaded using LoadDumpedFingerprint.
+func (x *Client) FingerprintForLookup(lookupID uint64) ([]byte, bool) {
+	return x.fingerprints.get(lookupID)
+}
+
+// SearchUntil performs a metadata search of the fingerprint and returns
+// the first match, in the order returned by the backend service, for
+// which predicate returns true. If no match satisfies the predicate,
+// nil is returned without an error. If ctx is done before the search
+// finishes, ctx.Err() is returned.
+//
+// The backend service doesn't stream matches, so the whole result is
+// always retrieved before the predicate is evaluated. Cancelling ctx
+// only stops the waiting; it doesn't save any backend work.
+func (x *Client) SearchUntil(ctx context.Context, ft *Fingerprint, predicate func(*MetadataSearchMatch) bool) (*MetadataSearchMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fut, err := x.MetadataSearch.Start(&MetadataSearchRequest{
+		Fingerprint: ft,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := fut.wait(ctx.Done())
+	if err == ErrCancelled && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range res.Matches {
+		if predicate(m) {
+			return m, nil
+		}
+	}
+	return nil, nil
+}
+
+// CancelAll cancels all searches started by the client that are still
+// in progress. Pending and subsequent calls to Get on their futures
+// return ErrCancelled immediately, without waiting for the backend
+// service. Start calls that are in progress return ErrCancelled as soon
+// as their network operation finishes. Searches started after CancelAll
+// returns are not affected.
+//
+// The searches are not aborted on the backend service, their resources
+// are released once the core library returns.
+func (x *Client) CancelAll() {
+	x.ops.cancelAll()
+}
+
 // Close closes all connections to the backend service and releases
 // the memory manually allocated by the core library. The
 // AssetLibrary, LicenseSearch and MetadataSearch fields must not be
 // used after Close is called.
+//
+// Close first cancels all outstanding operations like CancelAll, and
+// then waits for the calls still running in the core library to return
+// before releasing the memory.
 func (x *Client) Close() error {
+	x.ops.close()
 	C.AE_AssetLibrary_Delete(&x.AssetLibrary.c)
 	C.AE_MetadataSearch_Delete(&x.MetadataSearch.c)
 	C.AE_Client_Delete(&x.c)
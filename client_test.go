@@ -1,6 +1,9 @@
 package pexae
 
-import "testing"
+import (
+	"context"
+	"testing"
+)
 
 func TestClientWithValidCredentials(t *testing.T) {
 	client, err := NewMockserverClient("client01", "secret01")
@@ -44,3 +47,171 @@ func TestClientWithInvalidCredentials(t *testing.T) {
 		t.Fatalf("got invalid error code, expected %d, got %d", StatusUnauthenticated, e.Code)
 	}
 }
+
+func newTestClient(t *testing.T) *Client {
+	client, err := NewMockserverClient("client01", "secret01")
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	return client
+}
+
+func newTestFingerprint(t *testing.T) *Fingerprint {
+	ft, err := LoadDumpedFingerprint([]byte("mockserver fingerprint"))
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	return ft
+}
+
+func startTestSearches(t *testing.T, client *Client, ft *Fingerprint, n int) []*MetadataSearchFuture {
+	var futures []*MetadataSearchFuture
+	for i := 0; i < n; i++ {
+		fut, err := client.MetadataSearch.Start(&MetadataSearchRequest{
+			Fingerprint: ft,
+			UserData:    i,
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %+v", err)
+		}
+		futures = append(futures, fut)
+	}
+	return futures
+}
+
+func TestSearchUntil(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	fut := startTestSearches(t, client, ft, 1)[0]
+	res, err := fut.Get()
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if len(res.Matches) == 0 {
+		t.Fatal("expected the mockserver to return at least one match")
+	}
+	last := res.Matches[len(res.Matches)-1]
+
+	m, err := client.SearchUntil(context.Background(), ft, func(m *MetadataSearchMatch) bool {
+		return m.AssetID == last.AssetID
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if m == nil || m.AssetID != last.AssetID {
+		t.Fatalf("expected a match of asset %d, got %+v", last.AssetID, m)
+	}
+}
+
+func TestSearchUntilNoMatch(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	m, err := client.SearchUntil(context.Background(), ft, func(*MetadataSearchMatch) bool {
+		return false
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if m != nil {
+		t.Fatalf("expected no match, got %+v", m)
+	}
+}
+
+func TestSearchUntilCancelled(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	m, err := client.SearchUntil(ctx, ft, func(*MetadataSearchMatch) bool {
+		t.Fatal("expected the predicate not to be called")
+		return true
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected %v, got %+v", context.Canceled, err)
+	}
+	if m != nil {
+		t.Fatalf("expected no match, got %+v", m)
+	}
+}
+
+func TestClientCancelAll(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	const n = 20
+	futures := startTestSearches(t, client, ft, n)
+
+	var licenseFutures []*LicenseSearchFuture
+	for i := 0; i < n; i++ {
+		fut, err := client.LicenseSearch.Start(&LicenseSearchRequest{Fingerprint: ft})
+		if err != nil {
+			t.Fatalf("expected no error, got %+v", err)
+		}
+		licenseFutures = append(licenseFutures, fut)
+	}
+
+	client.CancelAll()
+
+	for i, fut := range futures {
+		if res, err := fut.Get(); err != ErrCancelled {
+			t.Fatalf("expected ErrCancelled for metadata search %d, got %+v, %+v", i, res, err)
+		}
+	}
+	for i, fut := range licenseFutures {
+		if res, err := fut.Get(); err != ErrCancelled {
+			t.Fatalf("expected ErrCancelled for license search %d, got %+v, %+v", i, res, err)
+		}
+	}
+
+	// Searches started after CancelAll are not affected.
+	fut := startTestSearches(t, client, ft, 1)[0]
+	if _, err := fut.Get(); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+}
+
+func TestClientCloseCancels(t *testing.T) {
+	client := newTestClient(t)
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	fut := startTestSearches(t, client, ft, 1)[0]
+	licenseFut, err := client.LicenseSearch.Start(&LicenseSearchRequest{Fingerprint: ft})
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("closing the client returned error: %+v", err)
+	}
+
+	if _, err := fut.Get(); err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled, got %+v", err)
+	}
+	if _, err := licenseFut.Get(); err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled, got %+v", err)
+	}
+	if _, err := client.MetadataSearch.Start(&MetadataSearchRequest{Fingerprint: ft}); err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled, got %+v", err)
+	}
+	if _, err := client.LicenseSearch.Start(&LicenseSearchRequest{Fingerprint: ft}); err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled, got %+v", err)
+	}
+}
@@ -2,6 +2,8 @@
 
 package pexae
 
+import "time"
+
 // Segment is the range [start, end) in both the query and the asset of
 // where the match was found within the asset.
 type Segment struct {
@@ -17,3 +19,33 @@ type Segment struct {
 	// The end of the matched range in the asset in seconds (exclusive).
 	AssetEnd int64
 }
+
+// QueryRange returns the matched range in the query.
+func (s *Segment) QueryRange() Range {
+	return Range{
+		Start: time.Duration(s.QueryStart) * time.Second,
+		End:   time.Duration(s.QueryEnd) * time.Second,
+	}
+}
+
+// AssetRange returns the matched range in the asset.
+func (s *Segment) AssetRange() Range {
+	return Range{
+		Start: time.Duration(s.AssetStart) * time.Second,
+		End:   time.Duration(s.AssetEnd) * time.Second,
+	}
+}
+
+// Range is the range [start, end) within a piece of content.
+type Range struct {
+	// The start of the range (inclusive).
+	Start time.Duration
+
+	// The end of the range (exclusive).
+	End time.Duration
+}
+
+// Duration returns the length of the range.
+func (r Range) Duration() time.Duration {
+	return r.End - r.Start
+}
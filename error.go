@@ -4,7 +4,10 @@ package pexae
 
 // #include <pex/ae/sdk/c/status.h>
 import "C"
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 // StatusCode is used together with Error as a hint on why the error
 // was returned.
@@ -25,6 +28,10 @@ const (
 	StatusLookupTimedOut   = StatusCode(11)
 )
 
+// ErrCancelled is returned when waiting for an operation was cancelled
+// before the operation finished.
+var ErrCancelled = errors.New("cancelled")
+
 // Error will be returend by most SDK functions. Besides an error
 // message, it also includes a status code, which can be used to
 // determine the underlying issue, e.g. AssetLibrary.GetAsset will return
@@ -5,7 +5,11 @@ package pexae
 // #include <stdlib.h>
 // #include <pex/ae/sdk/c/fingerprint.h>
 import "C"
-import "unsafe"
+import (
+	"io"
+	"io/ioutil"
+	"unsafe"
+)
 
 // Fingerprint is how the SDK identifies a piece of digital content.
 // It can be generated from a media file or from a memory buffer. The
@@ -51,6 +55,17 @@ func LoadDumpedFingerprint(dump []byte) (*Fingerprint, error) {
 	return &Fingerprint{ft}, nil
 }
 
+// LoadDumpedFingerprintFromReader loads a fingerprint previously
+// serialized by the Fingerprint.DumpTo() or Fingerprint.Dump()
+// functions. It reads r until EOF.
+func LoadDumpedFingerprintFromReader(r io.Reader) (*Fingerprint, error) {
+	dump, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return LoadDumpedFingerprint(dump)
+}
+
 func newFingerprint(input []byte, isFile bool) (*Fingerprint, error) {
 	status := C.AE_Status_New()
 	if status == nil {
@@ -113,3 +128,53 @@ func (f *Fingerprint) Dump() []byte {
 
 	return C.GoBytes(data, size)
 }
+
+// DumpTo serializes the fingerprint and writes it to w. Unlike Dump, it
+// writes directly from the memory allocated by the core library without
+// copying the data into a Go byte slice first. It returns the number of
+// bytes written. If a write fails or is short, the returned error is
+// from w or io.ErrShortWrite, respectively.
+func (f *Fingerprint) DumpTo(w io.Writer) (int64, error) {
+	b := C.AE_Buffer_New()
+	if b == nil {
+		panic("out of memory")
+	}
+	defer C.AE_Buffer_Delete(&b)
+
+	C.AE_Fingerprint_Dump(f.ft, b)
+
+	data := unsafe.Pointer(C.AE_Buffer_GetData(b))
+	size := int(C.AE_Buffer_GetSize(b))
+
+	// The slices point to C memory, which stays valid until the buffer
+	// is deleted when this function returns.
+	return writeChunks(w, size, maxDumpChunk, func(off, n int) []byte {
+		return (*[maxDumpChunk]byte)(unsafe.Pointer(uintptr(data) + uintptr(off)))[:n:n]
+	})
+}
+
+// maxDumpChunk is the largest chunk of C memory that DumpTo passes to
+// a single Write.
+const maxDumpChunk = 1 << 30
+
+// writeChunks writes size bytes to w in chunks of at most max bytes,
+// where chunk returns the n bytes starting at off.
+func writeChunks(w io.Writer, size, max int, chunk func(off, n int) []byte) (int64, error) {
+	var written int64
+	for off := 0; off < size; off += max {
+		n := size - off
+		if n > max {
+			n = max
+		}
+
+		m, err := w.Write(chunk(off, n))
+		written += int64(m)
+		if err != nil {
+			return written, err
+		}
+		if m < n {
+			return written, io.ErrShortWrite
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WithFingerprintRetention makes the client retain the serialized
+// fingerprints of the last n searches started by LicenseSearch.Start
+// and MetadataSearch.Start, so that they can be retrieved later using
+// Client.FingerprintForLookup. Once more than n fingerprints are
+// retained, the oldest one is dropped. The fingerprints are not
+// retained by default, since every retained dump is held in memory.
+func WithFingerprintRetention(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.fingerprintRetention = n
+	}
+}
+
+// fingerprintCache holds a bounded number of fingerprint dumps keyed by
+// the LookupID of the search they were used in.
+type fingerprintCache struct {
+	size    int
+	order   *list.List
+	entries map[uint64]*list.Element
+	m       sync.Mutex
+}
+
+type fingerprintCacheEntry struct {
+	lookupID uint64
+	dump     []byte
+}
+
+func newFingerprintCache(size int) *fingerprintCache {
+	if size <= 0 {
+		return nil
+	}
+	return &fingerprintCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[uint64]*list.Element),
+	}
+}
+
+// add stores the dump of the fingerprint. It is safe to call on a nil
+// cache, in which case the fingerprint is not dumped at all.
+func (c *fingerprintCache) add(lookupID uint64, ft *Fingerprint) {
+	if c == nil {
+		return
+	}
+
+	c.put(lookupID, ft.Dump())
+}
+
+func (c *fingerprintCache) put(lookupID uint64, dump []byte) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if e, ok := c.entries[lookupID]; ok {
+		e.Value.(*fingerprintCacheEntry).dump = dump
+		return
+	}
+
+	c.entries[lookupID] = c.order.PushBack(&fingerprintCacheEntry{
+		lookupID: lookupID,
+		dump:     dump,
+	})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*fingerprintCacheEntry).lookupID)
+	}
+}
+
+func (c *fingerprintCache) get(lookupID uint64) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	e, ok := c.entries[lookupID]
+	if !ok {
+		return nil, false
+	}
+
+	dump := e.Value.(*fingerprintCacheEntry).dump
+	cp := make([]byte, len(dump))
+	copy(cp, dump)
+	return cp, true
+}
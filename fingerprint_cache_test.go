@@ -0,0 +1,79 @@
+package pexae
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFingerprintCache(t *testing.T) {
+	c := newFingerprintCache(2)
+	c.put(1, []byte{1})
+	c.put(2, []byte{2})
+	c.put(3, []byte{3})
+
+	if _, ok := c.get(1); ok {
+		t.Fatal("expected the oldest dump to be dropped")
+	}
+	for _, id := range []uint64{2, 3} {
+		if dump, ok := c.get(id); !ok || !bytes.Equal(dump, []byte{byte(id)}) {
+			t.Fatalf("expected dump %d to be retained, got %v, %v", id, dump, ok)
+		}
+	}
+
+	// Mutating the returned dump must not affect the cache.
+	dump, _ := c.get(2)
+	dump[0] = 42
+	if dump, _ := c.get(2); !bytes.Equal(dump, []byte{2}) {
+		t.Fatalf("expected the cached dump to be unchanged, got %v", dump)
+	}
+
+	var disabled *fingerprintCache
+	if _, ok := disabled.get(2); ok {
+		t.Fatal("expected nothing to be retained by a disabled cache")
+	}
+}
+
+func TestWithFingerprintRetention(t *testing.T) {
+	client, err := NewMockserverClient("client01", "secret01", WithFingerprintRetention(2))
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	fut := startTestSearches(t, client, ft, 1)[0]
+	licenseFut, err := client.LicenseSearch.Start(&LicenseSearchRequest{Fingerprint: ft})
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	for _, lookupID := range []uint64{fut.LookupID, licenseFut.LookupID} {
+		dump, ok := client.FingerprintForLookup(lookupID)
+		if !ok {
+			t.Fatalf("expected the fingerprint of lookup %d to be retained", lookupID)
+		}
+		if expected := ft.Dump(); !bytes.Equal(dump, expected) {
+			t.Fatalf("expected %q, got %q", expected, dump)
+		}
+	}
+
+	fut.Get()
+	licenseFut.Get()
+}
+
+func TestWithoutFingerprintRetention(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	fut := startTestSearches(t, client, ft, 1)[0]
+	defer fut.Get()
+
+	if dump, ok := client.FingerprintForLookup(fut.LookupID); ok || dump != nil {
+		t.Fatalf("expected no fingerprint, got %q, %v", dump, ok)
+	}
+}
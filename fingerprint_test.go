@@ -0,0 +1,124 @@
+package pexae
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestFingerprintDumpTo(t *testing.T) {
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	var buf bytes.Buffer
+	n, err := ft.DumpTo(&buf)
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("expected %d bytes written, got %d", buf.Len(), n)
+	}
+	if expected := ft.Dump(); !bytes.Equal(buf.Bytes(), expected) {
+		t.Fatalf("expected %q, got %q", expected, buf.Bytes())
+	}
+
+	loaded, err := LoadDumpedFingerprintFromReader(&buf)
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	defer loaded.Close()
+
+	if expected, got := ft.Dump(), loaded.Dump(); !bytes.Equal(got, expected) {
+		t.Fatalf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestLoadDumpedFingerprintFromReaderError(t *testing.T) {
+	failure := errors.New("read failed")
+
+	ft, err := LoadDumpedFingerprintFromReader(&errorReader{err: failure})
+	if err != failure {
+		t.Fatalf("expected %v, got %+v", failure, err)
+	}
+	if ft != nil {
+		t.Fatalf("expected no fingerprint, got %+v", ft)
+	}
+}
+
+func TestWriteChunks(t *testing.T) {
+	data := []byte("0123456789")
+	chunk := func(off, n int) []byte {
+		return data[off : off+n]
+	}
+
+	tests := []struct {
+		name    string
+		w       *limitWriter
+		written int64
+		err     error
+		writes  []string
+	}{{
+		name:    "all",
+		w:       &limitWriter{limit: 10},
+		written: 10,
+		writes:  []string{"0123", "4567", "89"},
+	}, {
+		name:    "short",
+		w:       &limitWriter{limit: 6},
+		written: 6,
+		err:     io.ErrShortWrite,
+		writes:  []string{"0123", "4567"},
+	}, {
+		name:    "error",
+		w:       &limitWriter{limit: 4, err: io.ErrClosedPipe},
+		written: 4,
+		err:     io.ErrClosedPipe,
+		writes:  []string{"0123", "4567"},
+	}}
+
+	for _, tt := range tests {
+		written, err := writeChunks(tt.w, len(data), 4, chunk)
+		if written != tt.written {
+			t.Fatalf("%s: expected %d bytes written, got %d", tt.name, tt.written, written)
+		}
+		if err != tt.err {
+			t.Fatalf("%s: expected %v, got %+v", tt.name, tt.err, err)
+		}
+		if !reflect.DeepEqual(tt.w.writes, tt.writes) {
+			t.Fatalf("%s: expected writes %q, got %q", tt.name, tt.writes, tt.w.writes)
+		}
+	}
+}
+
+type errorReader struct {
+	err error
+}
+
+func (r *errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// limitWriter records the writes and accepts at most limit bytes. The
+// write that exceeds the limit is short and returns err.
+type limitWriter struct {
+	limit  int
+	err    error
+	writes []string
+}
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+
+	n := len(p)
+	if n > w.limit {
+		n = w.limit
+	}
+	w.limit -= n
+
+	if n < len(p) {
+		return n, w.err
+	}
+	return n, nil
+}
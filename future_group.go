@@ -0,0 +1,148 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import "sync"
+
+// FutureGroup manages a collection of metadata search futures that are
+// awaited or cancelled together. The zero value is ready to use.
+type FutureGroup struct {
+	futures []*MetadataSearchFuture
+	labels  []string
+
+	cancel     chan struct{}
+	cancelOnce sync.Once
+	m          sync.Mutex
+}
+
+// Add adds a future to the group.
+func (x *FutureGroup) Add(f *MetadataSearchFuture) {
+	x.AddLabeled("", f)
+}
+
+// AddLabeled adds a future to the group together with a label that can
+// later be retrieved using the Labels method, e.g. to correlate the
+// results with the uploads they belong to.
+func (x *FutureGroup) AddLabeled(label string, f *MetadataSearchFuture) {
+	x.m.Lock()
+	defer x.m.Unlock()
+
+	x.futures = append(x.futures, f)
+	x.labels = append(x.labels, label)
+}
+
+// Len returns the number of futures in the group.
+func (x *FutureGroup) Len() int {
+	x.m.Lock()
+	defer x.m.Unlock()
+
+	return len(x.futures)
+}
+
+// Labels returns the labels of the futures in the order in which they
+// were added. Futures added using Add have an empty label.
+func (x *FutureGroup) Labels() []string {
+	x.m.Lock()
+	defer x.m.Unlock()
+
+	labels := make([]string, len(x.labels))
+	copy(labels, x.labels)
+	return labels
+}
+
+// Wait blocks until all futures in the group are finished and returns
+// their results and errors. Both slices are in the order in which the
+// futures were added, and for every index exactly one of the result and
+// the error is set. Futures that haven't finished by the time Cancel is
+// called report ErrCancelled.
+func (x *FutureGroup) Wait() ([]*MetadataSearchResult, []error) {
+	x.m.Lock()
+	futures := make([]*MetadataSearchFuture, len(x.futures))
+	copy(futures, x.futures)
+	cancel := x.cancelChan()
+	x.m.Unlock()
+
+	results := make([]*MetadataSearchResult, len(futures))
+	errs := make([]error, len(futures))
+
+	var wg sync.WaitGroup
+	for i, f := range futures {
+		wg.Add(1)
+		go func(i int, f *MetadataSearchFuture) {
+			defer wg.Done()
+			results[i], errs[i] = f.wait(cancel)
+		}(i, f)
+	}
+	wg.Wait()
+
+	return results, errs
+}
+
+// Cancel makes all pending and future calls to Wait return ErrCancelled
+// for the futures that haven't finished yet. The searches themselves
+// are not aborted on the backend, their resources are released once
+// they finish. It is safe to call Cancel multiple times.
+func (x *FutureGroup) Cancel() {
+	x.m.Lock()
+	cancel := x.cancelChan()
+	x.m.Unlock()
+
+	x.cancelOnce.Do(func() {
+		close(cancel)
+	})
+}
+
+// cancelChan must be called with the mutex held.
+func (x *FutureGroup) cancelChan() chan struct{} {
+	if x.cancel == nil {
+		x.cancel = make(chan struct{})
+	}
+	return x.cancel
+}
+
+// IndexedResult is sent by WaitAllStream whenever one of the futures
+// finishes.
+type IndexedResult struct {
+	// The index of the future in the slice passed to WaitAllStream.
+	Index int
+
+	// The result of the search, nil if Err is set.
+	Result *MetadataSearchResult
+
+	// The error returned by MetadataSearchFuture.Get.
+	Err error
+
+	// The value of MetadataSearchFuture.UserData, which is available
+	// even when Err is set.
+	UserData interface{}
+}
+
+// WaitAllStream waits for all futures in parallel and sends their
+// results to the returned channel in the order in which they finish.
+// The channel is closed once all futures are finished. It is buffered
+// for all the results, so the goroutines waiting for the futures never
+// block, even if the caller stops receiving.
+func WaitAllStream(futures []*MetadataSearchFuture) <-chan IndexedResult {
+	ch := make(chan IndexedResult, len(futures))
+
+	var wg sync.WaitGroup
+	for i, f := range futures {
+		wg.Add(1)
+		go func(i int, f *MetadataSearchFuture) {
+			defer wg.Done()
+			res, err := f.Get()
+			ch <- IndexedResult{
+				Index:    i,
+				Result:   res,
+				Err:      err,
+				UserData: f.UserData,
+			}
+		}(i, f)
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
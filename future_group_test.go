@@ -0,0 +1,108 @@
+package pexae
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFutureGroupWait(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	futures := startTestSearches(t, client, ft, 3)
+
+	var group FutureGroup
+	group.AddLabeled("first", futures[0])
+	group.Add(futures[1])
+	group.AddLabeled("third", futures[2])
+
+	if n := group.Len(); n != 3 {
+		t.Fatalf("expected 3 futures, got %d", n)
+	}
+	if expected, got := []string{"first", "", "third"}, group.Labels(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected labels %q, got %q", expected, got)
+	}
+
+	results, errs := group.Wait()
+	if len(results) != 3 || len(errs) != 3 {
+		t.Fatalf("expected 3 results and errors, got %d and %d", len(results), len(errs))
+	}
+	for i, res := range results {
+		if errs[i] != nil {
+			t.Fatalf("expected no error for future %d, got %+v", i, errs[i])
+		}
+		if res.LookupID != futures[i].LookupID {
+			t.Fatalf("expected result %d to belong to lookup %d, got %d", i, futures[i].LookupID, res.LookupID)
+		}
+		if res.UserData != i {
+			t.Fatalf("expected result %d to carry user data %d, got %+v", i, i, res.UserData)
+		}
+	}
+}
+
+func TestFutureGroupCancel(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	var group FutureGroup
+	for _, fut := range startTestSearches(t, client, ft, 3) {
+		group.Add(fut)
+	}
+
+	group.Cancel()
+	group.Cancel()
+
+	results, errs := group.Wait()
+	for i := range results {
+		if results[i] != nil {
+			t.Fatalf("expected no result for future %d, got %+v", i, results[i])
+		}
+		if errs[i] != ErrCancelled {
+			t.Fatalf("expected ErrCancelled for future %d, got %+v", i, errs[i])
+		}
+	}
+}
+
+func TestWaitAllStream(t *testing.T) {
+	client := newTestClient(t)
+	defer client.Close()
+
+	ft := newTestFingerprint(t)
+	defer ft.Close()
+
+	const n = 5
+	futures := startTestSearches(t, client, ft, n)
+
+	seen := make(map[int]bool)
+	for r := range WaitAllStream(futures) {
+		if seen[r.Index] {
+			t.Fatalf("got future %d more than once", r.Index)
+		}
+		seen[r.Index] = true
+
+		if r.Err != nil {
+			t.Fatalf("expected no error for future %d, got %+v", r.Index, r.Err)
+		}
+		if r.Result.LookupID != futures[r.Index].LookupID {
+			t.Fatalf("expected result %d to belong to lookup %d, got %d", r.Index, futures[r.Index].LookupID, r.Result.LookupID)
+		}
+		if r.UserData != r.Index {
+			t.Fatalf("expected result %d to carry user data %d, got %+v", r.Index, r.Index, r.UserData)
+		}
+	}
+
+	// The loop above only ends once the channel is closed.
+	if len(seen) != n {
+		t.Fatalf("expected %d results before the channel was closed, got %d", n, len(seen))
+	}
+
+	if r, ok := <-WaitAllStream(nil); ok {
+		t.Fatalf("expected the channel to be closed, got %+v", r)
+	}
+}
@@ -53,13 +53,30 @@ type LicenseSearchResult struct {
 // search. Instead of instantiating the class directly,
 // Client.LicenseSearch should be used.
 type LicenseSearch struct {
-	c *C.AE_LicenseSearch
+	c            *C.AE_LicenseSearch
+	breaker      *circuitBreaker
+	fingerprints *fingerprintCache
+	ops          *operations
 }
 
 // Starts a license search. This operation does not block until the
 // search is finished, it does however perform a network operation to
 // initiate the search on the backend service.
 func (x *LicenseSearch) Start(req *LicenseSearchRequest) (*LicenseSearchFuture, error) {
+	if err := x.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	// The fingerprint is borrowed by the core library until
+	// AE_LicenseSearch_Start returns, so unlike Get, Start can't be
+	// abandoned half-way when the client's operations are cancelled.
+	if !x.ops.begin() {
+		x.breaker.release()
+		return nil, ErrCancelled
+	}
+	defer x.ops.end()
+	cancel := x.ops.cancelChan()
+
 	cStatus := C.AE_Status_New()
 	if cStatus == nil {
 		panic("out of memory")
@@ -81,14 +98,30 @@ func (x *LicenseSearch) Start(req *LicenseSearchRequest) (*LicenseSearchFuture,
 
 	C.AE_LicenseSearch_Start(x.c, cRequest, cFuture, cStatus)
 	if err := statusToError(cStatus); err != nil {
+		x.breaker.recordStart(err)
+
 		// Delete the resource here to prevent leaking.
 		C.AE_LicenseSearchFuture_Delete(&cFuture)
 		return nil, err
 	}
 
+	select {
+	case <-cancel:
+		x.breaker.release()
+		C.AE_LicenseSearchFuture_Delete(&cFuture)
+		return nil, ErrCancelled
+	default:
+	}
+
+	lookupID := uint64(C.AE_LicenseSearchFuture_GetLookupID(cFuture))
+	x.fingerprints.add(lookupID, req.Fingerprint)
+
 	return &LicenseSearchFuture{
-		LookupID: uint64(C.AE_LicenseSearchFuture_GetLookupID(cFuture)),
+		LookupID: lookupID,
 		c:        cFuture,
+		breaker:  x.breaker,
+		ops:      x.ops,
+		cancel:   cancel,
 	}, nil
 }
 
@@ -97,14 +130,31 @@ func (x *LicenseSearch) Start(req *LicenseSearchRequest) (*LicenseSearchFuture,
 type LicenseSearchFuture struct {
 	LookupID uint64
 
-	c *C.AE_LicenseSearchFuture
-	m sync.Mutex
+	c       *C.AE_LicenseSearchFuture
+	m       sync.Mutex
+	breaker *circuitBreaker
+	ops     *operations
+	cancel  <-chan struct{}
 }
 
 // Get blocks until the search result is ready and then returns it. It
 // also releases all the allocated resources, so it will return an
-// error when called multiple times.
+// error when called multiple times. If Client.CancelAll is called
+// before the result is ready, ErrCancelled is returned immediately
+// and the resources are released once the search finishes.
 func (x *LicenseSearchFuture) Get() (*LicenseSearchResult, error) {
+	var res *LicenseSearchResult
+	var err error
+
+	if cErr := x.ops.do(x.cancel, nil, func() {
+		res, err = x.get()
+	}); cErr != nil {
+		return nil, cErr
+	}
+	return res, err
+}
+
+func (x *LicenseSearchFuture) get() (*LicenseSearchResult, error) {
 	x.m.Lock()
 	defer x.m.Unlock()
 
@@ -127,8 +177,11 @@ func (x *LicenseSearchFuture) Get() (*LicenseSearchResult, error) {
 
 	C.AE_LicenseSearchFuture_Get(x.c, cResult, cStatus)
 	if err := statusToError(cStatus); err != nil {
+		x.breaker.recordGet(err)
 		return nil, err
 	}
+	x.breaker.recordGet(nil)
+
 	return x.processResult(cResult), nil
 }
 
@@ -8,6 +8,7 @@ import "C"
 import (
 	"errors"
 	"sync"
+	"time"
 )
 
 // Holds all data necessary to perform a metadata search. A search can only be
@@ -17,6 +18,12 @@ type MetadataSearchRequest struct {
 	// A fingerprint obtained by calling either NewFingerprintFromFile
 	// or NewFingerprintFromBuffer. This field is required.
 	Fingerprint *Fingerprint
+
+	// Arbitrary data that is not sent to the backend service, but is
+	// carried unchanged onto the MetadataSearchFuture and
+	// MetadataSearchResult, e.g. to correlate them with the upload they
+	// belong to.
+	UserData interface{}
 }
 
 // This object is returned from MetadataSearchFuture.Get upon successful
@@ -29,8 +36,17 @@ type MetadataSearchResult struct {
 	// An ID that uniquely identifies the UGC. It is used to provide UGC metadata back to Pex.
 	UGCID uint64
 
-	// A list of matches.
+	// A list of matches, in the order in which they were returned by
+	// the backend service.
 	Matches []*MetadataSearchMatch
+
+	// The time (in UTC) when the search completed, i.e. when the result
+	// was retrieved by MetadataSearchFuture.Get.
+	SearchedAt time.Time
+
+	// The value of MetadataSearchRequest.UserData. It is not included
+	// when the result is serialized.
+	UserData interface{}
 }
 
 // MetadataSearchMatch contains detailed information about the match,
@@ -44,19 +60,41 @@ type MetadataSearchMatch struct {
 
 	// A list of matching segments.
 	Segments []*Segment
+
+	// The position of the match in the order returned by the backend
+	// service, starting at 1. It is preserved when the matches are
+	// reordered, e.g. by MetadataSearchFilter.TopN.
+	Rank int
 }
 
 // This class encapsulates all operations necessary to perform a
 // metadata search. Instead of instantiating the class directly,
 // Client.MetadataSearch should be used.
 type MetadataSearch struct {
-	c *C.AE_MetadataSearch
+	c            *C.AE_MetadataSearch
+	breaker      *circuitBreaker
+	fingerprints *fingerprintCache
+	ops          *operations
 }
 
 // Start starts a metadata search. This operation does not block until
 // the search is finished, it does however perform a network operation
 // to initiate the search on the backend service.
 func (x *MetadataSearch) Start(req *MetadataSearchRequest) (*MetadataSearchFuture, error) {
+	if err := x.breaker.allow(); err != nil {
+		return nil, err
+	}
+
+	// The fingerprint is borrowed by the core library until
+	// AE_MetadataSearch_Start returns, so unlike Get, Start can't be
+	// abandoned half-way when the client's operations are cancelled.
+	if !x.ops.begin() {
+		x.breaker.release()
+		return nil, ErrCancelled
+	}
+	defer x.ops.end()
+	cancel := x.ops.cancelChan()
+
 	cStatus := C.AE_Status_New()
 	if cStatus == nil {
 		panic("out of memory")
@@ -78,14 +116,31 @@ func (x *MetadataSearch) Start(req *MetadataSearchRequest) (*MetadataSearchFutur
 
 	C.AE_MetadataSearch_Start(x.c, cRequest, cFuture, cStatus)
 	if err := statusToError(cStatus); err != nil {
+		x.breaker.recordStart(err)
+
 		// Delete the resource here to prevent leaking.
 		C.AE_MetadataSearchFuture_Delete(&cFuture)
 		return nil, err
 	}
 
+	select {
+	case <-cancel:
+		x.breaker.release()
+		C.AE_MetadataSearchFuture_Delete(&cFuture)
+		return nil, ErrCancelled
+	default:
+	}
+
+	lookupID := uint64(C.AE_MetadataSearchFuture_GetLookupID(cFuture))
+	x.fingerprints.add(lookupID, req.Fingerprint)
+
 	return &MetadataSearchFuture{
-		LookupID: uint64(C.AE_MetadataSearchFuture_GetLookupID(cFuture)),
+		LookupID: lookupID,
+		UserData: req.UserData,
 		c:        cFuture,
+		breaker:  x.breaker,
+		ops:      x.ops,
+		cancel:   cancel,
 	}, nil
 }
 
@@ -94,14 +149,39 @@ func (x *MetadataSearch) Start(req *MetadataSearchRequest) (*MetadataSearchFutur
 type MetadataSearchFuture struct {
 	LookupID uint64
 
-	c *C.AE_MetadataSearchFuture
-	m sync.Mutex
+	// The value of MetadataSearchRequest.UserData.
+	UserData interface{}
+
+	c       *C.AE_MetadataSearchFuture
+	m       sync.Mutex
+	breaker *circuitBreaker
+	ops     *operations
+	cancel  <-chan struct{}
 }
 
 // Get blocks until the search result is ready and then returns it. It
 // also releases all the allocated resources, so it will return an
-// error when called multiple times.
+// error when called multiple times. If Client.CancelAll is called
+// before the result is ready, ErrCancelled is returned immediately
+// and the resources are released once the search finishes.
 func (x *MetadataSearchFuture) Get() (*MetadataSearchResult, error) {
+	return x.wait(nil)
+}
+
+// wait is like Get, but also returns ErrCancelled when cancel is closed.
+func (x *MetadataSearchFuture) wait(cancel <-chan struct{}) (*MetadataSearchResult, error) {
+	var res *MetadataSearchResult
+	var err error
+
+	if cErr := x.ops.do(x.cancel, cancel, func() {
+		res, err = x.get()
+	}); cErr != nil {
+		return nil, cErr
+	}
+	return res, err
+}
+
+func (x *MetadataSearchFuture) get() (*MetadataSearchResult, error) {
 	x.m.Lock()
 	defer x.m.Unlock()
 
@@ -124,8 +204,11 @@ func (x *MetadataSearchFuture) Get() (*MetadataSearchResult, error) {
 
 	C.AE_MetadataSearchFuture_Get(x.c, cResult, cStatus)
 	if err := statusToError(cStatus); err != nil {
+		x.breaker.recordGet(err)
 		return nil, err
 	}
+	x.breaker.recordGet(nil)
+
 	return x.processResult(cResult), nil
 }
 
@@ -164,12 +247,15 @@ func (x *MetadataSearchFuture) processResult(cResult *C.AE_MetadataSearchResult)
 		matches = append(matches, &MetadataSearchMatch{
 			AssetID:  uint64(C.AE_MetadataSearchMatch_GetAssetID(cMatch)),
 			Segments: segments,
+			Rank:     len(matches) + 1,
 		})
 	}
 
 	return &MetadataSearchResult{
-		LookupID: uint64(C.AE_MetadataSearchResult_GetLookupID(cResult)),
-		UGCID:    uint64(C.AE_MetadataSearchResult_GetUGCID(cResult)),
-		Matches:  matches,
+		LookupID:   uint64(C.AE_MetadataSearchResult_GetLookupID(cResult)),
+		UGCID:      uint64(C.AE_MetadataSearchResult_GetUGCID(cResult)),
+		Matches:    matches,
+		SearchedAt: time.Now().UTC(),
+		UserData:   x.UserData,
 	}
 }
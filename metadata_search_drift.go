@@ -0,0 +1,63 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import "fmt"
+
+// ResultDrift describes how the matches of a UGC changed between two
+// searches performed at different times. It is returned by
+// DetectDrift.
+type ResultDrift struct {
+	// The ID of the UGC both results belong to.
+	UGCID uint64
+
+	// Matches from the newer result against assets that weren't matched
+	// by the older result, e.g. because they were ingested in the
+	// meantime.
+	NewMatches []*MetadataSearchMatch
+
+	// Matches from the older result against assets that are no longer
+	// matched by the newer result.
+	RemovedMatches []*MetadataSearchMatch
+}
+
+// Changed reports whether any match appeared or disappeared.
+func (x *ResultDrift) Changed() bool {
+	return len(x.NewMatches) > 0 || len(x.RemovedMatches) > 0
+}
+
+// DetectDrift compares an older and a newer result of searching the
+// same UGC and reports the matches that appeared or disappeared. Matches
+// are compared by their AssetID only, changes in the matched segments of
+// an asset that's present in both results are not reported. An error
+// is returned if the results belong to different UGCs.
+func DetectDrift(older, newer *MetadataSearchResult) (*ResultDrift, error) {
+	if older.UGCID != newer.UGCID {
+		return nil, fmt.Errorf("results belong to different UGCs: %d and %d", older.UGCID, newer.UGCID)
+	}
+
+	olderIDs := make(map[uint64]bool, len(older.Matches))
+	for _, m := range older.Matches {
+		olderIDs[m.AssetID] = true
+	}
+
+	newerIDs := make(map[uint64]bool, len(newer.Matches))
+	for _, m := range newer.Matches {
+		newerIDs[m.AssetID] = true
+	}
+
+	drift := &ResultDrift{
+		UGCID: newer.UGCID,
+	}
+	for _, m := range newer.Matches {
+		if !olderIDs[m.AssetID] {
+			drift.NewMatches = append(drift.NewMatches, m)
+		}
+	}
+	for _, m := range older.Matches {
+		if !newerIDs[m.AssetID] {
+			drift.RemovedMatches = append(drift.RemovedMatches, m)
+		}
+	}
+	return drift, nil
+}
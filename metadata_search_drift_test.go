@@ -0,0 +1,30 @@
+package pexae
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	m1, m2, m3 := newTestMatch(1), newTestMatch(2), newTestMatch(3)
+
+	drift, err := DetectDrift(newTestResult(m1, m2), newTestResult(newTestMatch(2), m3))
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if !drift.Changed() {
+		t.Fatal("expected the results to differ")
+	}
+	if expected := []*MetadataSearchMatch{m3}; !reflect.DeepEqual(drift.NewMatches, expected) {
+		t.Fatalf("expected new matches %+v, got %+v", expected, drift.NewMatches)
+	}
+	if expected := []*MetadataSearchMatch{m1}; !reflect.DeepEqual(drift.RemovedMatches, expected) {
+		t.Fatalf("expected removed matches %+v, got %+v", expected, drift.RemovedMatches)
+	}
+
+	other := newTestResult()
+	other.UGCID++
+	if _, err := DetectDrift(newTestResult(), other); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import (
+	"sort"
+	"time"
+)
+
+// MetadataSearchFilter is used to post-process the matches of a
+// MetadataSearchResult. It is returned by MetadataSearchResult.Filter
+// and the individual steps can be chained together, e.g.:
+//
+//	res = res.Filter().MinDuration(5 * time.Second).BestPerAsset().TopN(10).Build()
+//
+// Every step returns a new filter, leaving the one it was called on
+// unchanged. The steps are applied lazily, in the order they were added,
+// when Build is called. The original result is never modified.
+type MetadataSearchFilter struct {
+	res   *MetadataSearchResult
+	steps []func([]*MetadataSearchMatch) []*MetadataSearchMatch
+}
+
+// Filter returns a MetadataSearchFilter operating on the matches of
+// the result.
+func (x *MetadataSearchResult) Filter() *MetadataSearchFilter {
+	return &MetadataSearchFilter{res: x}
+}
+
+// MinDuration drops all matches whose segments cover less than d of
+// the query. See MetadataSearchMatch.QueryDuration.
+func (x *MetadataSearchFilter) MinDuration(d time.Duration) *MetadataSearchFilter {
+	return x.add(func(matches []*MetadataSearchMatch) []*MetadataSearchMatch {
+		var filtered []*MetadataSearchMatch
+		for _, m := range matches {
+			if m.QueryDuration() >= d {
+				filtered = append(filtered, m)
+			}
+		}
+		return filtered
+	})
+}
+
+// MinAssetCoverage drops all matches that cover less than the given
+// fraction (0..1) of their asset. The SDK doesn't know the durations of
+// the assets, so they are provided by the assetDuration function, e.g.
+// backed by the caller's own metadata store. Matches whose asset
+// duration is unknown are kept.
+//
+// The coverage is the total duration of the asset returned by
+// MetadataSearchMatch.UniqueAssetRanges divided by the asset duration,
+// so every part of the asset is only counted once, even if it matched
+// multiple times.
+func (x *MetadataSearchFilter) MinAssetCoverage(threshold float64, assetDuration func(assetID uint64) (time.Duration, bool)) *MetadataSearchFilter {
+	return x.add(func(matches []*MetadataSearchMatch) []*MetadataSearchMatch {
+		var filtered []*MetadataSearchMatch
+		for _, m := range matches {
+			d, ok := assetDuration(m.AssetID)
+			if !ok || m.AssetCoverage(d) >= threshold {
+				filtered = append(filtered, m)
+			}
+		}
+		return filtered
+	})
+}
+
+// BestPerAsset keeps only a single match for every asset, the one that
+// covers the longest part of the query. The relative order of the kept
+// matches is preserved.
+func (x *MetadataSearchFilter) BestPerAsset() *MetadataSearchFilter {
+	return x.add(func(matches []*MetadataSearchMatch) []*MetadataSearchMatch {
+		best := make(map[uint64]int)
+		durations := make([]time.Duration, len(matches))
+
+		for i, m := range matches {
+			durations[i] = m.QueryDuration()
+			if j, ok := best[m.AssetID]; !ok || durations[i] > durations[j] {
+				best[m.AssetID] = i
+			}
+		}
+
+		var filtered []*MetadataSearchMatch
+		for i, m := range matches {
+			if best[m.AssetID] == i {
+				filtered = append(filtered, m)
+			}
+		}
+		return filtered
+	})
+}
+
+// TopN keeps at most n matches that cover the longest part of the
+// query. The kept matches are sorted by the covered duration in
+// descending order, matches with equal duration keep their relative
+// order.
+func (x *MetadataSearchFilter) TopN(n int) *MetadataSearchFilter {
+	return x.add(func(matches []*MetadataSearchMatch) []*MetadataSearchMatch {
+		if n <= 0 {
+			return nil
+		}
+
+		sorted := make([]*MetadataSearchMatch, len(matches))
+		copy(sorted, matches)
+
+		durations := make(map[*MetadataSearchMatch]time.Duration, len(sorted))
+		for _, m := range sorted {
+			durations[m] = m.QueryDuration()
+		}
+
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return durations[sorted[i]] > durations[sorted[j]]
+		})
+
+		if len(sorted) > n {
+			sorted = sorted[:n]
+		}
+		return sorted
+	})
+}
+
+// Build applies all the steps and returns a new result containing only
+// the matches that passed through them. The matches themselves are
+// shared with the original result.
+func (x *MetadataSearchFilter) Build() *MetadataSearchResult {
+	matches := x.res.Matches
+	for _, step := range x.steps {
+		matches = step(matches)
+	}
+
+	res := *x.res
+	res.Matches = matches
+	return &res
+}
+
+func (x *MetadataSearchFilter) add(step func([]*MetadataSearchMatch) []*MetadataSearchMatch) *MetadataSearchFilter {
+	// Copy the steps so that branching off of an intermediate filter
+	// doesn't affect the other branches.
+	steps := make([]func([]*MetadataSearchMatch) []*MetadataSearchMatch, len(x.steps), len(x.steps)+1)
+	copy(steps, x.steps)
+
+	return &MetadataSearchFilter{
+		res:   x.res,
+		steps: append(steps, step),
+	}
+}
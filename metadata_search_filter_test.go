@@ -0,0 +1,55 @@
+package pexae
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	m1 := newTestMatch(1, &Segment{QueryStart: 0, QueryEnd: 3})
+	m2 := newTestMatch(1, &Segment{QueryStart: 0, QueryEnd: 8})
+	m3 := newTestMatch(2, &Segment{QueryStart: 0, QueryEnd: 1})
+	m4 := newTestMatch(3, &Segment{QueryStart: 0, QueryEnd: 5}, &Segment{QueryStart: 2, QueryEnd: 6})
+	res := newTestResult(m1, m2, m3, m4)
+
+	filter := res.Filter().MinDuration(sec(2))
+
+	got := filter.BestPerAsset().TopN(1).Build()
+	if expected := []*MetadataSearchMatch{m2}; !reflect.DeepEqual(got.Matches, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got.Matches)
+	}
+	if got.LookupID != res.LookupID || got.UGCID != res.UGCID {
+		t.Fatalf("expected IDs to be preserved, got %+v", got)
+	}
+
+	got = filter.Build()
+	if expected := []*MetadataSearchMatch{m1, m2, m4}; !reflect.DeepEqual(got.Matches, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got.Matches)
+	}
+
+	if len(res.Matches) != 4 {
+		t.Fatalf("expected the original result to be unchanged, got %+v", res.Matches)
+	}
+}
+
+func TestMinAssetCoverage(t *testing.T) {
+	m1 := newTestMatch(1, &Segment{AssetStart: 0, AssetEnd: 50})
+	m2 := newTestMatch(2, &Segment{AssetStart: 0, AssetEnd: 5}, &Segment{AssetStart: 3, AssetEnd: 8})
+	m3 := newTestMatch(3, &Segment{AssetStart: 0, AssetEnd: 1})
+	res := newTestResult(m1, m2, m3)
+
+	durations := map[uint64]time.Duration{1: sec(100), 2: sec(100)}
+	got := res.Filter().MinAssetCoverage(0.5, func(id uint64) (time.Duration, bool) {
+		d, ok := durations[id]
+		return d, ok
+	}).Build()
+
+	if expected := []*MetadataSearchMatch{m1, m3}; !reflect.DeepEqual(got.Matches, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got.Matches)
+	}
+
+	if c := m2.AssetCoverage(sec(100)); c != 0.08 {
+		t.Fatalf("expected coverage 0.08, got %v", c)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// metadataSearchResultVersion is the current version of the serialized
+// MetadataSearchResult schema. It must be bumped whenever the schema
+// changes in a way older decoders can't handle, and a decoder for the
+// previous version must be kept around. Adding an optional field
+// doesn't require a new version, since it's simply left at its zero
+// value when decoding data that doesn't have it.
+//
+// Versions:
+//
+//	0: the default encoding/json encoding of the struct, without an envelope
+//	1: versioned envelope with snake_case field names
+const metadataSearchResultVersion = 1
+
+type metadataSearchResultEnvelope struct {
+	Version *int            `json:"version"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type metadataSearchResultV1 struct {
+	LookupID uint64                   `json:"lookup_id"`
+	UGCID    uint64                   `json:"ugc_id"`
+	Matches  []*metadataSearchMatchV1 `json:"matches"`
+
+	// RFC 3339 formatted, added without bumping the version.
+	SearchedAt string `json:"searched_at,omitempty"`
+}
+
+type metadataSearchMatchV1 struct {
+	AssetID  uint64       `json:"asset_id"`
+	Segments []*segmentV1 `json:"segments"`
+
+	// Added without bumping the version.
+	Rank int `json:"rank,omitempty"`
+}
+
+type segmentV1 struct {
+	QueryStart int64 `json:"query_start"`
+	QueryEnd   int64 `json:"query_end"`
+	AssetStart int64 `json:"asset_start"`
+	AssetEnd   int64 `json:"asset_end"`
+}
+
+// metadataSearchResultV0 has the same layout as MetadataSearchResult had
+// before the versioned envelope was introduced, but without the custom
+// (un)marshaling methods.
+type metadataSearchResultV0 struct {
+	LookupID uint64
+	UGCID    uint64
+	Matches  []*struct {
+		AssetID  uint64
+		Segments []*Segment
+	}
+}
+
+// MarshalJSON serializes the result into a versioned envelope, so that
+// results persisted by one version of the SDK can be decoded by any
+// later version. It has a value receiver so that the envelope is also
+// used for results that aren't addressable, e.g. map values.
+func (x MetadataSearchResult) MarshalJSON() ([]byte, error) {
+	res := &metadataSearchResultV1{
+		LookupID: x.LookupID,
+		UGCID:    x.UGCID,
+		Matches:  make([]*metadataSearchMatchV1, 0, len(x.Matches)),
+	}
+	if !x.SearchedAt.IsZero() {
+		res.SearchedAt = x.SearchedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	for _, m := range x.Matches {
+		match := &metadataSearchMatchV1{
+			AssetID:  m.AssetID,
+			Segments: make([]*segmentV1, 0, len(m.Segments)),
+			Rank:     m.Rank,
+		}
+		for _, s := range m.Segments {
+			match.Segments = append(match.Segments, &segmentV1{
+				QueryStart: s.QueryStart,
+				QueryEnd:   s.QueryEnd,
+				AssetStart: s.AssetStart,
+				AssetEnd:   s.AssetEnd,
+			})
+		}
+		res.Matches = append(res.Matches, match)
+	}
+
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+
+	version := metadataSearchResultVersion
+	return json.Marshal(&metadataSearchResultEnvelope{
+		Version: &version,
+		Result:  b,
+	})
+}
+
+// UnmarshalJSON deserializes a result previously serialized by
+// MarshalJSON. It accepts all previous versions of the schema, fields
+// that didn't exist in the decoded version are left at their zero
+// values. An error is returned if the data was serialized by a newer,
+// unknown version.
+func (x *MetadataSearchResult) UnmarshalJSON(b []byte) error {
+	var envelope metadataSearchResultEnvelope
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		return err
+	}
+
+	// Results serialized before the envelope was introduced don't have
+	// a version.
+	if envelope.Version == nil {
+		return x.unmarshalV0(b)
+	}
+
+	switch *envelope.Version {
+	case 1:
+		return x.unmarshalV1(envelope.Result)
+	default:
+		return fmt.Errorf("unsupported metadata search result version: %d", *envelope.Version)
+	}
+}
+
+func (x *MetadataSearchResult) unmarshalV0(b []byte) error {
+	var res metadataSearchResultV0
+	if err := json.Unmarshal(b, &res); err != nil {
+		return err
+	}
+
+	*x = MetadataSearchResult{
+		LookupID: res.LookupID,
+		UGCID:    res.UGCID,
+	}
+
+	// The matches were serialized in the order returned by the backend
+	// service.
+	for i, m := range res.Matches {
+		x.Matches = append(x.Matches, &MetadataSearchMatch{
+			AssetID:  m.AssetID,
+			Segments: m.Segments,
+			Rank:     i + 1,
+		})
+	}
+	return nil
+}
+
+func (x *MetadataSearchResult) unmarshalV1(b []byte) error {
+	var res metadataSearchResultV1
+	if err := json.Unmarshal(b, &res); err != nil {
+		return err
+	}
+
+	*x = MetadataSearchResult{
+		LookupID: res.LookupID,
+		UGCID:    res.UGCID,
+	}
+
+	if res.SearchedAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, res.SearchedAt)
+		if err != nil {
+			return err
+		}
+		x.SearchedAt = t.UTC()
+	}
+
+	for _, m := range res.Matches {
+		var segments []*Segment
+		for _, s := range m.Segments {
+			segments = append(segments, &Segment{
+				QueryStart: s.QueryStart,
+				QueryEnd:   s.QueryEnd,
+				AssetStart: s.AssetStart,
+				AssetEnd:   s.AssetEnd,
+			})
+		}
+
+		// Results serialized before the rank was added have the
+		// matches in the order returned by the backend service.
+		rank := m.Rank
+		if rank == 0 {
+			rank = len(x.Matches) + 1
+		}
+
+		x.Matches = append(x.Matches, &MetadataSearchMatch{
+			AssetID:  m.AssetID,
+			Segments: segments,
+			Rank:     rank,
+		})
+	}
+	return nil
+}
+
+type metadataSearchRecord struct {
+	LookupID   uint64       `json:"lookup_id"`
+	UGCID      uint64       `json:"ugc_id"`
+	SearchedAt string       `json:"searched_at,omitempty"`
+	AssetID    uint64       `json:"asset_id"`
+	Rank       int          `json:"rank,omitempty"`
+	Segments   []*segmentV1 `json:"segments"`
+}
+
+// WriteNDJSON writes the result to w as newline-delimited JSON, one
+// object per match. Every object contains the fields of the match
+// together with the result-level lookup_id, ugc_id and searched_at,
+// using the same field names as MarshalJSON. Nothing is written for a
+// result without matches.
+//
+// If w has a Flush method, like *bufio.Writer or http.Flusher, it is
+// called after every record. The first error returned by w (or its
+// Flush method) is returned, and no further records are written.
+func (x *MetadataSearchResult) WriteNDJSON(w io.Writer) error {
+	var searchedAt string
+	if !x.SearchedAt.IsZero() {
+		searchedAt = x.SearchedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	for _, m := range x.Matches {
+		rec := &metadataSearchRecord{
+			LookupID:   x.LookupID,
+			UGCID:      x.UGCID,
+			SearchedAt: searchedAt,
+			AssetID:    m.AssetID,
+			Rank:       m.Rank,
+			Segments:   make([]*segmentV1, 0, len(m.Segments)),
+		}
+		for _, s := range m.Segments {
+			rec.Segments = append(rec.Segments, &segmentV1{
+				QueryStart: s.QueryStart,
+				QueryEnd:   s.QueryEnd,
+				AssetStart: s.AssetStart,
+				AssetEnd:   s.AssetEnd,
+			})
+		}
+
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+
+		switch f := w.(type) {
+		case interface{ Flush() error }:
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		case interface{ Flush() }:
+			f.Flush()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package pexae
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func expectedFixtureResult() *MetadataSearchResult {
+	return &MetadataSearchResult{
+		LookupID: 1,
+		UGCID:    2,
+		Matches: []*MetadataSearchMatch{{
+			AssetID: 3,
+			Rank:    1,
+			Segments: []*Segment{
+				{QueryStart: 0, QueryEnd: 10, AssetStart: 20, AssetEnd: 30},
+				{QueryStart: 15, QueryEnd: 20, AssetStart: 35, AssetEnd: 40},
+			},
+		}, {
+			AssetID: 4,
+			Rank:    2,
+			Segments: []*Segment{
+				{QueryStart: 5, QueryEnd: 8, AssetStart: 0, AssetEnd: 3},
+			},
+		}},
+	}
+}
+
+func TestMetadataSearchResultDecodeFixtures(t *testing.T) {
+	fixtures := []string{
+		"testdata/metadata_search_result_v0.json",
+		"testdata/metadata_search_result_v1.json",
+	}
+
+	for _, fixture := range fixtures {
+		b, err := ioutil.ReadFile(fixture)
+		if err != nil {
+			t.Fatalf("%s: failed to read the fixture: %+v", fixture, err)
+		}
+
+		var got MetadataSearchResult
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("%s: expected no error, got %+v", fixture, err)
+		}
+
+		if expected := expectedFixtureResult(); !reflect.DeepEqual(&got, expected) {
+			t.Fatalf("%s: expected %+v, got %+v", fixture, expected, &got)
+		}
+	}
+}
+
+func TestMetadataSearchResultRoundTrip(t *testing.T) {
+	expected := expectedFixtureResult()
+	expected.SearchedAt = time.Date(2020, 6, 1, 12, 30, 15, 500, time.UTC)
+
+	b, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	if !strings.Contains(string(b), `"searched_at":"2020-06-01T12:30:15.0000005Z"`) {
+		t.Fatalf("expected an RFC 3339 timestamp, got %s", b)
+	}
+
+	var got MetadataSearchResult
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	if !reflect.DeepEqual(&got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, &got)
+	}
+}
+
+func TestMetadataSearchResultMarshalValue(t *testing.T) {
+	res := expectedFixtureResult()
+	res.SearchedAt = time.Date(2020, 6, 1, 12, 30, 15, 0, time.UTC)
+	// UserData must never be serialized, not even when it can't be.
+	res.UserData = func() {}
+
+	value, err := json.Marshal(*res)
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	m, err := json.Marshal(map[string]MetadataSearchResult{"res": *res})
+	if err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	res.UserData = nil
+	for _, b := range [][]byte{value, m} {
+		if !strings.Contains(string(b), `"version":1`) {
+			t.Fatalf("expected a versioned envelope, got %s", b)
+		}
+	}
+
+	var got MetadataSearchResult
+	if err := json.Unmarshal(value, &got); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if !reflect.DeepEqual(&got, res) {
+		t.Fatalf("expected %+v, got %+v", res, &got)
+	}
+
+	var gotMap map[string]MetadataSearchResult
+	if err := json.Unmarshal(m, &gotMap); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if got := gotMap["res"]; !reflect.DeepEqual(&got, res) {
+		t.Fatalf("expected %+v, got %+v", res, &got)
+	}
+}
+
+func TestMetadataSearchResultUnknownVersion(t *testing.T) {
+	var got MetadataSearchResult
+	if err := json.Unmarshal([]byte(`{"version": 999, "result": {}}`), &got); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+type flushCounter struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (x *flushCounter) Flush() error {
+	x.flushes++
+	return nil
+}
+
+func TestMetadataSearchResultWriteNDJSON(t *testing.T) {
+	res := expectedFixtureResult()
+
+	var w flushCounter
+	if err := res.WriteNDJSON(&w); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	expected := `{"lookup_id":1,"ugc_id":2,"asset_id":3,"rank":1,"segments":[{"query_start":0,"query_end":10,"asset_start":20,"asset_end":30},{"query_start":15,"query_end":20,"asset_start":35,"asset_end":40}]}
+{"lookup_id":1,"ugc_id":2,"asset_id":4,"rank":2,"segments":[{"query_start":5,"query_end":8,"asset_start":0,"asset_end":3}]}
+`
+	if got := w.String(); got != expected {
+		t.Fatalf("expected %s, got %s", expected, got)
+	}
+	if w.flushes != 2 {
+		t.Fatalf("expected 2 flushes, got %d", w.flushes)
+	}
+}
+
+func TestMetadataSearchResultWriteNDJSONError(t *testing.T) {
+	res := expectedFixtureResult()
+
+	var all bytes.Buffer
+	if err := res.WriteNDJSON(&all); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	record := strings.SplitAfter(all.String(), "\n")[0]
+
+	// The writer accepts the first record and fails on the second one.
+	w := &limitWriter{limit: len(record), err: io.ErrClosedPipe}
+	if err := res.WriteNDJSON(w); err != io.ErrClosedPipe {
+		t.Fatalf("expected %v, got %+v", io.ErrClosedPipe, err)
+	}
+	if len(w.writes) != 2 || w.writes[0] != record {
+		t.Fatalf("expected %q to be the only complete write, got %q", record, w.writes)
+	}
+}
+
+type voidFlusher struct {
+	bytes.Buffer
+	flushes int
+}
+
+func (x *voidFlusher) Flush() {
+	x.flushes++
+}
+
+func TestMetadataSearchResultWriteNDJSONVoidFlush(t *testing.T) {
+	res := expectedFixtureResult()
+
+	var w voidFlusher
+	if err := res.WriteNDJSON(&w); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+	if n := strings.Count(w.String(), "\n"); n != 2 {
+		t.Fatalf("expected 2 records, got %d", n)
+	}
+	if w.flushes != 2 {
+		t.Fatalf("expected 2 flushes, got %d", w.flushes)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import (
+	"sort"
+	"time"
+)
+
+// ReportRow describes a single range of the query in the report
+// returned by MetadataSearchResult.SegmentReport.
+type ReportRow struct {
+	// The range of the query covered by the row.
+	Query Range
+
+	// Whether any asset matched the range. If it's false, the range is
+	// clean, i.e. it didn't match anything.
+	Matched bool
+
+	// The IDs of all assets that matched the whole range, sorted in
+	// ascending order. It's empty for clean rows.
+	AssetIDs []uint64
+}
+
+// SegmentReport partitions the query [0, totalDuration) into rows that
+// are either matched or clean. The rows are sorted, don't overlap and
+// leave no gaps. Adjacent ranges matched by the same set of assets are
+// merged into a single row, so a new row starts whenever an asset
+// starts or stops matching. The clean rows are the same ranges as the
+// ones returned by UnmatchedQueryRanges.
+func (x *MetadataSearchResult) SegmentReport(totalDuration time.Duration) []ReportRow {
+	if totalDuration <= 0 {
+		return nil
+	}
+
+	type assetRange struct {
+		assetID uint64
+		r       Range
+	}
+
+	var ranges []assetRange
+	bounds := []time.Duration{0, totalDuration}
+
+	for _, m := range x.Matches {
+		for _, s := range m.Segments {
+			r := s.QueryRange()
+			if r.Start < 0 {
+				r.Start = 0
+			}
+			if r.End > totalDuration {
+				r.End = totalDuration
+			}
+			if r.Start >= r.End {
+				continue
+			}
+			ranges = append(ranges, assetRange{m.AssetID, r})
+			bounds = append(bounds, r.Start, r.End)
+		}
+	}
+
+	sort.Slice(bounds, func(i, j int) bool {
+		return bounds[i] < bounds[j]
+	})
+
+	var rows []ReportRow
+	for i := 1; i < len(bounds); i++ {
+		start, end := bounds[i-1], bounds[i]
+		if start == end {
+			continue
+		}
+
+		var ids []uint64
+		for _, ar := range ranges {
+			if ar.r.Start <= start && ar.r.End >= end {
+				ids = append(ids, ar.assetID)
+			}
+		}
+		ids = uniqueSorted(ids)
+
+		if n := len(rows); n > 0 && equalIDs(rows[n-1].AssetIDs, ids) {
+			rows[n-1].Query.End = end
+			continue
+		}
+
+		rows = append(rows, ReportRow{
+			Query:    Range{Start: start, End: end},
+			Matched:  len(ids) > 0,
+			AssetIDs: ids,
+		})
+	}
+	return rows
+}
+
+// uniqueSorted sorts the IDs and removes the duplicates. The input
+// slice is modified.
+func uniqueSorted(ids []uint64) []uint64 {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	unique := ids[:1]
+	for _, id := range ids[1:] {
+		if id != unique[len(unique)-1] {
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,29 @@
+package pexae
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSegmentReport(t *testing.T) {
+	res := newTestResult(
+		newTestMatch(1, &Segment{QueryStart: 2, QueryEnd: 6}),
+		newTestMatch(2, &Segment{QueryStart: 4, QueryEnd: 6}, &Segment{QueryStart: 8, QueryEnd: 12}),
+	)
+
+	expected := []ReportRow{
+		{Query: Range{Start: 0, End: sec(2)}},
+		{Query: Range{Start: sec(2), End: sec(4)}, Matched: true, AssetIDs: []uint64{1}},
+		{Query: Range{Start: sec(4), End: sec(6)}, Matched: true, AssetIDs: []uint64{1, 2}},
+		{Query: Range{Start: sec(6), End: sec(8)}},
+		{Query: Range{Start: sec(8), End: sec(10)}, Matched: true, AssetIDs: []uint64{2}},
+	}
+	if got := res.SegmentReport(sec(10)); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	expected = []ReportRow{{Query: Range{Start: 0, End: sec(10)}}}
+	if got := newTestResult().SegmentReport(sec(10)); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
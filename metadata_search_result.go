@@ -0,0 +1,220 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import (
+	"sort"
+	"time"
+)
+
+// AssetIDs returns the IDs of all matched assets. The returned IDs are
+// unique and sorted in ascending order.
+func (x *MetadataSearchResult) AssetIDs() []uint64 {
+	ids := make([]uint64, 0, len(x.Matches))
+	for _, m := range x.Matches {
+		ids = append(ids, m.AssetID)
+	}
+	return uniqueSorted(ids)
+}
+
+// UnmatchedQueryRanges returns the ranges of the query that didn't match
+// any asset, i.e. the complement of all matched query segments over
+// [0, totalDuration). The ranges are sorted and don't overlap. If the
+// whole query is matched, nil is returned. If nothing is matched, a
+// single range spanning the whole query is returned.
+func (x *MetadataSearchResult) UnmatchedQueryRanges(totalDuration time.Duration) []Range {
+	if totalDuration <= 0 {
+		return nil
+	}
+
+	var unmatched []Range
+	var pos time.Duration
+
+	for _, r := range x.matchedQueryRanges(totalDuration) {
+		if r.Start > pos {
+			unmatched = append(unmatched, Range{Start: pos, End: r.Start})
+		}
+		pos = r.End
+	}
+	if pos < totalDuration {
+		unmatched = append(unmatched, Range{Start: pos, End: totalDuration})
+	}
+	return unmatched
+}
+
+// QueryDuration returns the total duration of the query covered by the
+// match's segments. Overlapping segments are only counted once.
+func (x *MetadataSearchMatch) QueryDuration() time.Duration {
+	ranges := make([]Range, 0, len(x.Segments))
+	for _, s := range x.Segments {
+		if r := s.QueryRange(); r.Start < r.End {
+			ranges = append(ranges, r)
+		}
+	}
+
+	var total time.Duration
+	for _, r := range mergeRanges(ranges) {
+		total += r.Duration()
+	}
+	return total
+}
+
+// SegmentGaps returns the gaps in the query between consecutive
+// segments of the match, sorted by their query start. The result has
+// one element less than there are segments. Every gap is measured from
+// the furthest end of all the preceding segments to the start of the
+// next one, so a positive gap is always time not covered by any
+// segment. A negative gap means that the segment starts before the
+// preceding ones end, and its absolute value is how far before; for a
+// segment contained in a preceding one this is more than the length of
+// the segment itself.
+func (x *MetadataSearchMatch) SegmentGaps() []time.Duration {
+	if len(x.Segments) < 2 {
+		return nil
+	}
+
+	ranges := make([]Range, 0, len(x.Segments))
+	for _, s := range x.Segments {
+		ranges = append(ranges, s.QueryRange())
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+
+	gaps := make([]time.Duration, 0, len(ranges)-1)
+	end := ranges[0].End
+	for _, r := range ranges[1:] {
+		gaps = append(gaps, r.Start-end)
+		if r.End > end {
+			end = r.End
+		}
+	}
+	return gaps
+}
+
+// AssetRegionCount returns the number of discontiguous regions of the
+// asset covered by the match's segments. Segments whose asset ranges
+// overlap or touch are counted as a single region.
+func (x *MetadataSearchMatch) AssetRegionCount() int {
+	ranges := make([]Range, 0, len(x.Segments))
+	for _, s := range x.Segments {
+		if r := s.AssetRange(); r.Start < r.End {
+			ranges = append(ranges, r)
+		}
+	}
+	return len(mergeRanges(ranges))
+}
+
+// IsStitched reports whether the match covers more than one
+// discontiguous region of the asset, e.g. when the query is a
+// compilation of several parts of the asset. Note that a straight clip
+// of the asset is also reported as stitched if a part of it in the
+// middle didn't match.
+func (x *MetadataSearchMatch) IsStitched() bool {
+	return x.AssetRegionCount() > 1
+}
+
+// UniqueAssetRanges returns the parts of the asset that were matched,
+// mapped onto the asset's [0, assetDuration) timeline. This is useful
+// when a short asset loops within a longer query, in which case the
+// asset offsets of the segments keep growing past the asset's duration.
+//
+// The backend doesn't indicate whether a match is looped, so the
+// helper assumes that segments within [0, assetDuration) are not
+// looped and folds any offsets past the asset's end back to the start
+// (modulo assetDuration). A segment that wraps around the end of the
+// asset is split in two. The returned ranges are sorted and merged. If
+// assetDuration is not positive, nil is returned.
+func (x *MetadataSearchMatch) UniqueAssetRanges(assetDuration time.Duration) []Range {
+	if assetDuration <= 0 {
+		return nil
+	}
+
+	var ranges []Range
+	for _, s := range x.Segments {
+		r := s.AssetRange()
+		if r.Start < 0 || r.Start >= r.End {
+			continue
+		}
+
+		// The segment covers at least one whole loop.
+		if r.Duration() >= assetDuration {
+			return []Range{{Start: 0, End: assetDuration}}
+		}
+
+		start := r.Start % assetDuration
+		end := start + r.Duration()
+		if end <= assetDuration {
+			ranges = append(ranges, Range{Start: start, End: end})
+		} else {
+			ranges = append(ranges,
+				Range{Start: start, End: assetDuration},
+				Range{Start: 0, End: end - assetDuration})
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// AssetCoverage returns the fraction (0..1) of the asset covered by the
+// match, given the asset's duration. See UniqueAssetRanges for how
+// looped and overlapping segments are handled. If assetDuration is not
+// positive, 0 is returned.
+func (x *MetadataSearchMatch) AssetCoverage(assetDuration time.Duration) float64 {
+	if assetDuration <= 0 {
+		return 0
+	}
+
+	var covered time.Duration
+	for _, r := range x.UniqueAssetRanges(assetDuration) {
+		covered += r.Duration()
+	}
+	return float64(covered) / float64(assetDuration)
+}
+
+// matchedQueryRanges returns the union of all matched query segments
+// clamped to [0, totalDuration), sorted and with overlapping ranges
+// merged together.
+func (x *MetadataSearchResult) matchedQueryRanges(totalDuration time.Duration) []Range {
+	var ranges []Range
+	for _, m := range x.Matches {
+		for _, s := range m.Segments {
+			r := s.QueryRange()
+			if r.Start < 0 {
+				r.Start = 0
+			}
+			if r.End > totalDuration {
+				r.End = totalDuration
+			}
+			if r.Start < r.End {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+	return mergeRanges(ranges)
+}
+
+// mergeRanges sorts the ranges and merges the ones that overlap or
+// touch. The input slice is modified.
+func mergeRanges(ranges []Range) []Range {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
@@ -0,0 +1,165 @@
+package pexae
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func sec(n int64) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+func newTestResult(matches ...*MetadataSearchMatch) *MetadataSearchResult {
+	return &MetadataSearchResult{
+		LookupID: 1,
+		UGCID:    2,
+		Matches:  matches,
+	}
+}
+
+func newTestMatch(assetID uint64, segments ...*Segment) *MetadataSearchMatch {
+	return &MetadataSearchMatch{
+		AssetID:  assetID,
+		Segments: segments,
+	}
+}
+
+func TestUnmatchedQueryRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   *MetadataSearchResult
+		total    time.Duration
+		expected []Range
+	}{{
+		name:     "no matches",
+		result:   newTestResult(),
+		total:    sec(10),
+		expected: []Range{{Start: 0, End: sec(10)}},
+	}, {
+		name: "whole query matched",
+		result: newTestResult(
+			newTestMatch(1, &Segment{QueryStart: 0, QueryEnd: 6}),
+			newTestMatch(2, &Segment{QueryStart: 4, QueryEnd: 12}),
+		),
+		total:    sec(10),
+		expected: nil,
+	}, {
+		name: "gaps",
+		result: newTestResult(
+			newTestMatch(1, &Segment{QueryStart: 7, QueryEnd: 8}, &Segment{QueryStart: 2, QueryEnd: 4}),
+			newTestMatch(2, &Segment{QueryStart: 3, QueryEnd: 5}),
+		),
+		total: sec(10),
+		expected: []Range{
+			{Start: 0, End: sec(2)},
+			{Start: sec(5), End: sec(7)},
+			{Start: sec(8), End: sec(10)},
+		},
+	}, {
+		name:     "zero duration",
+		result:   newTestResult(),
+		total:    0,
+		expected: nil,
+	}}
+
+	for _, tt := range tests {
+		got := tt.result.UnmatchedQueryRanges(tt.total)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Fatalf("%s: expected %+v, got %+v", tt.name, tt.expected, got)
+		}
+	}
+}
+
+func TestAssetIDs(t *testing.T) {
+	res := newTestResult(newTestMatch(3), newTestMatch(1), newTestMatch(3), newTestMatch(2), newTestMatch(1))
+
+	expected := []uint64{1, 2, 3}
+	if got := res.AssetIDs(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	if got := newTestResult().AssetIDs(); got != nil {
+		t.Fatalf("expected nil, got %v", got)
+	}
+}
+
+func TestUniqueAssetRanges(t *testing.T) {
+	match := newTestMatch(1,
+		&Segment{AssetStart: 2, AssetEnd: 4},
+		&Segment{AssetStart: 12, AssetEnd: 15},
+		&Segment{AssetStart: 28, AssetEnd: 31},
+	)
+
+	expected := []Range{
+		{Start: 0, End: sec(1)},
+		{Start: sec(2), End: sec(5)},
+		{Start: sec(8), End: sec(10)},
+	}
+	if got := match.UniqueAssetRanges(sec(10)); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	match = newTestMatch(1, &Segment{AssetStart: 5, AssetEnd: 20})
+	expected = []Range{{Start: 0, End: sec(10)}}
+	if got := match.UniqueAssetRanges(sec(10)); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestIsStitched(t *testing.T) {
+	straight := newTestMatch(1,
+		&Segment{QueryStart: 0, QueryEnd: 5, AssetStart: 10, AssetEnd: 15},
+		&Segment{QueryStart: 5, QueryEnd: 10, AssetStart: 15, AssetEnd: 20},
+	)
+	if n := straight.AssetRegionCount(); n != 1 {
+		t.Fatalf("expected 1 region, got %d", n)
+	}
+	if straight.IsStitched() {
+		t.Fatal("expected a straight clip not to be stitched")
+	}
+
+	stitched := newTestMatch(1,
+		&Segment{QueryStart: 0, QueryEnd: 5, AssetStart: 60, AssetEnd: 65},
+		&Segment{QueryStart: 5, QueryEnd: 10, AssetStart: 10, AssetEnd: 15},
+		&Segment{QueryStart: 10, QueryEnd: 15, AssetStart: 120, AssetEnd: 125},
+	)
+	if n := stitched.AssetRegionCount(); n != 3 {
+		t.Fatalf("expected 3 regions, got %d", n)
+	}
+	if !stitched.IsStitched() {
+		t.Fatal("expected a compilation to be stitched")
+	}
+}
+
+func TestSegmentGaps(t *testing.T) {
+	match := newTestMatch(1,
+		&Segment{QueryStart: 10, QueryEnd: 15},
+		&Segment{QueryStart: 0, QueryEnd: 5},
+		&Segment{QueryStart: 13, QueryEnd: 20},
+		&Segment{QueryStart: 20, QueryEnd: 22},
+	)
+
+	expected := []time.Duration{sec(5), -sec(2), 0}
+	if got := match.SegmentGaps(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	// The time between the contained segments is covered by the first
+	// one, so there are no positive gaps.
+	contained := newTestMatch(1,
+		&Segment{QueryStart: 0, QueryEnd: 100},
+		&Segment{QueryStart: 10, QueryEnd: 20},
+		&Segment{QueryStart: 30, QueryEnd: 40},
+		&Segment{QueryStart: 110, QueryEnd: 120},
+	)
+
+	expected = []time.Duration{-sec(90), -sec(70), sec(10)}
+	if got := contained.SegmentGaps(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+
+	if got := newTestMatch(1, &Segment{QueryStart: 0, QueryEnd: 5}).SegmentGaps(); got != nil {
+		t.Fatalf("expected nil, got %v", got)
+	}
+}
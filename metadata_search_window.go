@@ -0,0 +1,112 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import (
+	"sort"
+	"time"
+)
+
+// WindowedResult is the result of searching a single window of a longer
+// file, together with the position of the window within the file.
+type WindowedResult struct {
+	// The start of the window within the whole file. Segments are
+	// reported in whole seconds, so the offset is truncated to seconds.
+	Offset time.Duration
+
+	// The result of searching the window.
+	Result *MetadataSearchResult
+}
+
+// StitchWindowedResults merges the results of searching (possibly
+// overlapping) windows of a file into a single result covering the
+// whole file. The query timestamps of all segments are shifted by the
+// offset of their window.
+//
+// The matches are grouped by AssetID. Segments of the same asset are
+// merged when they overlap or touch in the query and are aligned the
+// same way with the asset, i.e. have the same AssetStart - QueryStart
+// difference. This way a segment that straddles the boundary between
+// two windows, and is therefore reported by both, is counted only once.
+// Segments that match different parts of the asset are kept separate.
+//
+// The matches keep the order in which their assets first appear, which
+// is also reflected in their Rank, and the segments are sorted by their
+// query start. The LookupID and UGCID of the stitched result are taken
+// from the first non-nil result. The stitched result is nil if there
+// are no results.
+func StitchWindowedResults(results []*WindowedResult) *MetadataSearchResult {
+	var stitched *MetadataSearchResult
+	var order []uint64
+	segments := make(map[uint64][]*Segment)
+
+	for _, w := range results {
+		if w == nil || w.Result == nil {
+			continue
+		}
+		if stitched == nil {
+			stitched = &MetadataSearchResult{
+				LookupID: w.Result.LookupID,
+				UGCID:    w.Result.UGCID,
+			}
+		}
+
+		offset := int64(w.Offset / time.Second)
+		for _, m := range w.Result.Matches {
+			if _, ok := segments[m.AssetID]; !ok {
+				order = append(order, m.AssetID)
+				segments[m.AssetID] = nil
+			}
+			for _, s := range m.Segments {
+				segments[m.AssetID] = append(segments[m.AssetID], &Segment{
+					QueryStart: s.QueryStart + offset,
+					QueryEnd:   s.QueryEnd + offset,
+					AssetStart: s.AssetStart,
+					AssetEnd:   s.AssetEnd,
+				})
+			}
+		}
+	}
+
+	if stitched == nil {
+		return nil
+	}
+
+	for _, id := range order {
+		stitched.Matches = append(stitched.Matches, &MetadataSearchMatch{
+			AssetID:  id,
+			Segments: mergeAlignedSegments(segments[id]),
+			Rank:     len(stitched.Matches) + 1,
+		})
+	}
+	return stitched
+}
+
+// mergeAlignedSegments merges the segments that overlap or touch in the
+// query and have the same alignment with the asset. The result is
+// sorted by the query start. The input slice is modified.
+func mergeAlignedSegments(segments []*Segment) []*Segment {
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].QueryStart < segments[j].QueryStart
+	})
+
+	var merged []*Segment
+	// The last merged segment for every alignment.
+	last := make(map[int64]*Segment)
+
+	for _, s := range segments {
+		delta := s.AssetStart - s.QueryStart
+		if l, ok := last[delta]; ok && s.QueryStart <= l.QueryEnd {
+			if s.QueryEnd > l.QueryEnd {
+				l.QueryEnd = s.QueryEnd
+				l.AssetEnd = s.AssetEnd
+			}
+			continue
+		}
+
+		cp := *s
+		merged = append(merged, &cp)
+		last[delta] = &cp
+	}
+	return merged
+}
@@ -0,0 +1,48 @@
+package pexae
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStitchWindowedResults(t *testing.T) {
+	// Two 60 seconds long windows overlapping by 10 seconds. Asset 1
+	// straddles the boundary, asset 2 only appears in the second window.
+	first := newTestResult(
+		newTestMatch(1, &Segment{QueryStart: 40, QueryEnd: 60, AssetStart: 0, AssetEnd: 20}),
+	)
+	second := newTestResult(
+		newTestMatch(2, &Segment{QueryStart: 30, QueryEnd: 40, AssetStart: 100, AssetEnd: 110}),
+		newTestMatch(1,
+			&Segment{QueryStart: 0, QueryEnd: 20, AssetStart: 10, AssetEnd: 30},
+			&Segment{QueryStart: 40, QueryEnd: 45, AssetStart: 0, AssetEnd: 5},
+		),
+	)
+
+	got := StitchWindowedResults([]*WindowedResult{
+		{Offset: 0, Result: first},
+		{Offset: sec(50), Result: second},
+	})
+
+	expected := newTestResult(
+		newTestMatch(1,
+			&Segment{QueryStart: 40, QueryEnd: 70, AssetStart: 0, AssetEnd: 30},
+			&Segment{QueryStart: 90, QueryEnd: 95, AssetStart: 0, AssetEnd: 5},
+		),
+		newTestMatch(2, &Segment{QueryStart: 80, QueryEnd: 90, AssetStart: 100, AssetEnd: 110}),
+	)
+	expected.Matches[0].Rank = 1
+	expected.Matches[1].Rank = 2
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, got)
+	}
+
+	// The input must not be modified.
+	if s := second.Matches[1].Segments[0]; s.QueryStart != 0 || s.QueryEnd != 20 {
+		t.Fatalf("expected the input to be unchanged, got %+v", s)
+	}
+
+	if got := StitchWindowedResults(nil); got != nil {
+		t.Fatalf("expected nil, got %+v", got)
+	}
+}
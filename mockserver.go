@@ -8,7 +8,7 @@ import "C"
 import "unsafe"
 
 // NewMockserverClient creates a new instance of the client that will communicate with the mockserver using provided credentials for authentication.
-func NewMockserverClient(clientID, clientSecret string) (*Client, error) {
+func NewMockserverClient(clientID, clientSecret string, opts ...ClientOption) (*Client, error) {
 	cStatus := C.AE_Status_New()
 	if cStatus == nil {
 		panic("out of memory")
@@ -31,5 +31,5 @@ func NewMockserverClient(clientID, clientSecret string) (*Client, error) {
 		C.free(unsafe.Pointer(cClient))
 		return nil, err
 	}
-	return buildClient(cClient), nil
+	return buildClient(cClient, opts), nil
 }
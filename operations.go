@@ -0,0 +1,121 @@
+// Copyright 2020 Pexeso Inc. All rights reserved.
+
+package pexae
+
+import "sync"
+
+// operations tracks the calls into the core library made on behalf of
+// a single client. It allows cancelling all of them at once and lets
+// Close wait for the calls still running in the core library before the
+// client's resources are released.
+type operations struct {
+	cancel chan struct{}
+	closed bool
+	m      sync.Mutex
+	wg     sync.WaitGroup
+}
+
+func newOperations() *operations {
+	return &operations{
+		cancel: make(chan struct{}),
+	}
+}
+
+// cancelChan returns the channel that is closed by the next call to
+// cancelAll. Operations capture it when they are created, so that
+// cancelAll only affects operations that existed at the time it was
+// called.
+func (o *operations) cancelChan() <-chan struct{} {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	return o.cancel
+}
+
+func (o *operations) cancelAll() {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	// Everything was already cancelled by close.
+	if o.closed {
+		return
+	}
+
+	close(o.cancel)
+	o.cancel = make(chan struct{})
+}
+
+// begin registers a call into the core library. It returns false if the
+// client was already closed, in which case the call must not be made.
+func (o *operations) begin() bool {
+	o.m.Lock()
+	defer o.m.Unlock()
+
+	if o.closed {
+		return false
+	}
+	o.wg.Add(1)
+	return true
+}
+
+func (o *operations) end() {
+	o.wg.Done()
+}
+
+// do runs fn in a separate goroutine and waits until it returns, or
+// until either of the cancel channels is closed, in which case
+// ErrCancelled is returned immediately. fn keeps running in the
+// background after cancellation, so it must release any resources it
+// allocates itself, and the caller must not use anything fn sets when
+// ErrCancelled is returned.
+func (o *operations) do(cancel, extra <-chan struct{}, fn func()) error {
+	if !o.begin() {
+		return ErrCancelled
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer o.end()
+		fn()
+		close(done)
+	}()
+
+	// fn is still run when already cancelled, so that it releases its
+	// resources, but the result is never waited for.
+	if isClosed(cancel) || isClosed(extra) {
+		return ErrCancelled
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-cancel:
+		return ErrCancelled
+	case <-extra:
+		return ErrCancelled
+	}
+}
+
+// close cancels all operations, prevents new ones from being started
+// and waits until the ones still running in the core library return.
+func (o *operations) close() {
+	o.m.Lock()
+	if !o.closed {
+		o.closed = true
+		close(o.cancel)
+	}
+	o.m.Unlock()
+
+	o.wg.Wait()
+}
+
+// isClosed reports whether ch is closed without blocking. A nil channel
+// is never closed.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,76 @@
+package pexae
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOperationsCancelAll(t *testing.T) {
+	const n = 100
+
+	ops := newOperations()
+	release := make(chan struct{})
+
+	// Operations created before cancelAll capture the current channel.
+	cancel := ops.cancelChan()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- ops.do(cancel, nil, func() {
+				<-release
+			})
+		}()
+	}
+
+	ops.cancelAll()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelled operations didn't return promptly")
+	}
+
+	close(errs)
+	for err := range errs {
+		if err != ErrCancelled {
+			t.Fatalf("expected ErrCancelled, got %+v", err)
+		}
+	}
+
+	// Operations created after cancelAll are not affected.
+	if err := ops.do(ops.cancelChan(), nil, func() {}); err != nil {
+		t.Fatalf("expected no error, got %+v", err)
+	}
+
+	// close must wait for the abandoned calls to return.
+	closed := make(chan struct{})
+	go func() {
+		ops.close()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("close returned while calls were still running")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	<-closed
+
+	if err := ops.do(ops.cancelChan(), nil, func() {}); err != ErrCancelled {
+		t.Fatalf("expected ErrCancelled after close, got %+v", err)
+	}
+	ops.cancelAll()
+}